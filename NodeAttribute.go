@@ -2,11 +2,16 @@ package lslib
 
 import (
 	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
 	"gonum.org/v1/gonum/mat"
@@ -18,17 +23,28 @@ type XMLMarshaler interface {
 }
 
 type TranslatedString struct {
-	Version uint16
-	Value   string
-	Handle  string
+	Version uint16 `json:"version"`
+	Value   string `json:"value"`
+	Handle  string `json:"handle"`
 }
 
+// MarshalXML adds the handle, the value if it is set and the version to start
 func (ts TranslatedString) MarshalXML(e *xml.Encoder, start *xml.StartElement) error {
 	start.Attr = append(start.Attr,
 		xml.Attr{
 			Name:  xml.Name{Local: "handle"},
 			Value: ts.Handle,
 		},
+	)
+	if ts.Value != "" {
+		start.Attr = append(start.Attr,
+			xml.Attr{
+				Name:  xml.Name{Local: "value"},
+				Value: ts.Value,
+			},
+		)
+	}
+	start.Attr = append(start.Attr,
 		xml.Attr{
 			Name:  xml.Name{Local: "version"},
 			Value: strconv.Itoa(int(ts.Version)),
@@ -37,34 +53,184 @@ func (ts TranslatedString) MarshalXML(e *xml.Encoder, start *xml.StartElement) e
 	return nil
 }
 
+// UnmarshalXML reads the handle, version and value of a translated string from the attributes of start
+func (ts *TranslatedString) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	err := ts.unmarshalXMLAttr(start.Attr)
+	if err != nil {
+		return err
+	}
+	return d.Skip()
+}
+
+func (ts *TranslatedString) unmarshalXMLAttr(attrs []xml.Attr) error {
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "handle":
+			ts.Handle = a.Value
+
+		case "value":
+			ts.Value = a.Value
+
+		case "version":
+			v, err := strconv.ParseUint(a.Value, 10, 16)
+			if err != nil {
+				return fmt.Errorf("invalid translated string version %q: %w", a.Value, err)
+			}
+			ts.Version = uint16(v)
+		}
+	}
+	return nil
+}
+
 type TranslatedFSStringArgument struct {
-	String TranslatedFSString
-	Key    string
-	Value  string
+	String TranslatedFSString `json:"string"`
+	Key    string             `json:"key"`
+	Value  string             `json:"value"`
 }
 
 type TranslatedFSString struct {
 	TranslatedString
-	Arguments []TranslatedFSStringArgument
-}
-
-// func (tfs TranslatedFSString) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
-// 	start.Attr = append(start.Attr,
-// 		xml.Attr{
-// 			Name:  xml.Name{Local: "version"},
-// 			Value: strconv.Itoa(int(tfs.Version)),
-// 		},
-// 		xml.Attr{
-// 			Name:  xml.Name{Local: "handle"},
-// 			Value: tfs.Handle,
-// 		},
-// 		xml.Attr{
-// 			Name:  xml.Name{Local: "value"},
-// 			Value: ts.Value,
-// 		},
-// 	)
-// 	return nil
-// }
+	Arguments []TranslatedFSStringArgument `json:"arguments"`
+}
+
+// UnmarshalXML reads the argument key and value from the attributes of start
+// and the translated string from the nested string element
+func (tfsa *TranslatedFSStringArgument) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, a := range start.Attr {
+		switch a.Name.Local {
+		case "key":
+			tfsa.Key = a.Value
+
+		case "value":
+			tfsa.Value = a.Value
+		}
+	}
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "string" {
+				err = d.DecodeElement(&tfsa.String, &t)
+			} else {
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// UnmarshalXML reads the handle, version and value from the attributes of start
+// and rebuilds the Arguments from the nested arguments element
+func (tfs *TranslatedFSString) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	err := tfs.TranslatedString.unmarshalXMLAttr(start.Attr)
+	if err != nil {
+		return err
+	}
+	tfs.Arguments = tfs.Arguments[:0]
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "arguments":
+				// the arguments are read as they are encountered
+
+			case "argument":
+				var arg TranslatedFSStringArgument
+				err = d.DecodeElement(&arg, &t)
+				if err != nil {
+					return err
+				}
+				tfs.Arguments = append(tfs.Arguments, arg)
+
+			default:
+				err = d.Skip()
+				if err != nil {
+					return err
+				}
+			}
+
+		case xml.EndElement:
+			if t.Name.Local != "arguments" {
+				return nil
+			}
+		}
+	}
+}
+
+// marshalXML adds the handle, version and number of arguments to start,
+// the arguments themselves are child elements written by MarshalXMLArguments
+func (tfs TranslatedFSString) marshalXML(e *xml.Encoder, start *xml.StartElement) error {
+	err := tfs.TranslatedString.MarshalXML(e, start)
+	if err != nil {
+		return err
+	}
+	start.Attr = append(start.Attr,
+		xml.Attr{
+			Name:  xml.Name{Local: "arguments"},
+			Value: strconv.Itoa(len(tfs.Arguments)),
+		},
+	)
+	return nil
+}
+
+// MarshalXMLArguments writes the arguments element, it must be called after the element written by MarshalXML has been started
+func (tfs TranslatedFSString) MarshalXMLArguments(e *xml.Encoder) error {
+	if len(tfs.Arguments) == 0 {
+		return nil
+	}
+	arguments := xml.StartElement{Name: xml.Name{Local: "arguments"}}
+	err := e.EncodeToken(arguments)
+	if err != nil {
+		return err
+	}
+	for _, arg := range tfs.Arguments {
+		argument := xml.StartElement{
+			Name: xml.Name{Local: "argument"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "key"}, Value: arg.Key},
+				{Name: xml.Name{Local: "value"}, Value: arg.Value},
+			},
+		}
+		str := xml.StartElement{Name: xml.Name{Local: "string"}}
+		err = e.EncodeToken(argument)
+		if err != nil {
+			return err
+		}
+		err = arg.String.marshalXML(e, &str)
+		if err != nil {
+			return err
+		}
+		err = e.EncodeToken(str)
+		if err != nil {
+			return err
+		}
+		err = arg.String.MarshalXMLArguments(e)
+		if err != nil {
+			return err
+		}
+		err = e.EncodeToken(xml.EndElement{Name: str.Name})
+		if err != nil {
+			return err
+		}
+		err = e.EncodeToken(xml.EndElement{Name: argument.Name})
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: arguments.Name})
+}
 
 type Ivec []int
 
@@ -77,8 +243,179 @@ func (i Ivec) String() string {
 	return b.String()[1:]
 }
 
+func (i Ivec) component(n int) (int, error) {
+	if n >= len(i) {
+		return 0, ErrIndexOutOfRange
+	}
+	return i[n], nil
+}
+
+// withComponent returns a copy of i with component n set to v, i is extended with zeros if it is too short
+func (i Ivec) withComponent(n, v int) Ivec {
+	length := len(i)
+	if n >= length {
+		length = n + 1
+	}
+	vec := make(Ivec, length)
+	copy(vec, i)
+	vec[n] = v
+	return vec
+}
+
+// X returns the first component of i
+func (i Ivec) X() (int, error) { return i.component(0) }
+
+// Y returns the second component of i
+func (i Ivec) Y() (int, error) { return i.component(1) }
+
+// Z returns the third component of i
+func (i Ivec) Z() (int, error) { return i.component(2) }
+
+// W returns the fourth component of i
+func (i Ivec) W() (int, error) { return i.component(3) }
+
+// WithX returns a copy of i with the first component set to x
+func (i Ivec) WithX(x int) Ivec { return i.withComponent(0, x) }
+
+// WithY returns a copy of i with the second component set to y
+func (i Ivec) WithY(y int) Ivec { return i.withComponent(1, y) }
+
+// WithZ returns a copy of i with the third component set to z
+func (i Ivec) WithZ(z int) Ivec { return i.withComponent(2, z) }
+
+// WithW returns a copy of i with the fourth component set to w
+func (i Ivec) WithW(w int) Ivec { return i.withComponent(3, w) }
+
+func (i Ivec) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(i) > 4 {
+		return ErrVectorTooBig
+	}
+	start.Name.Local = "ivec" + strconv.Itoa(len(i))
+	for n, v := range i {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: "xyzw"[n : n+1]},
+			Value: strconv.Itoa(v),
+		})
+	}
+	e.EncodeToken(start)
+	e.EncodeToken(xml.EndElement{Name: start.Name})
+	return nil
+}
+
+// UnmarshalXML reads the x, y, z and w attributes of start into i
+func (i *Ivec) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var vec Ivec
+	for _, a := range start.Attr {
+		n := strings.Index("xyzw", a.Name.Local)
+		if len(a.Name.Local) != 1 || n < 0 {
+			continue
+		}
+		for len(vec) <= n {
+			vec = append(vec, 0)
+		}
+		v, err := strconv.ParseInt(a.Value, 0, 32)
+		if err != nil {
+			return err
+		}
+		vec[n] = int(v)
+	}
+	*i = vec
+	return d.Skip()
+}
+
 type Vec []float64
 
+func (v Vec) String() string {
+	b := &strings.Builder{}
+	for _, f := range v {
+		b.WriteString(" ")
+		b.WriteString(strconv.FormatFloat(f, 'f', -1, 32))
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return b.String()[1:]
+}
+
+// Sum returns the sum of the components of v
+func (v Vec) Sum() float64 {
+	var sum float64
+	for _, f := range v {
+		sum += f
+	}
+	return sum
+}
+
+// Mean returns the average of the components of v, it is NaN if v is empty
+func (v Vec) Mean() float64 {
+	return v.Sum() / float64(len(v))
+}
+
+// VecMax returns the component-wise maximum of vecs, which must all have the same length
+func VecMax(vecs []Vec) (Vec, error) {
+	return vecCombine(vecs, math.Max)
+}
+
+// VecMin returns the component-wise minimum of vecs, which must all have the same length
+func VecMin(vecs []Vec) (Vec, error) {
+	return vecCombine(vecs, math.Min)
+}
+
+// vecCombine folds the components of vecs at each index using combine
+func vecCombine(vecs []Vec, combine func(a, b float64) float64) (Vec, error) {
+	if len(vecs) == 0 {
+		return nil, errors.New("no vectors to combine")
+	}
+	result := append(Vec(nil), vecs[0]...)
+	for i, v := range vecs[1:] {
+		if len(v) != len(result) {
+			return nil, fmt.Errorf("vector %d has %d components, expected %d: %w", i+1, len(v), len(result), ErrDimensionMismatch)
+		}
+		for j, f := range v {
+			result[j] = combine(result[j], f)
+		}
+	}
+	return result, nil
+}
+
+// MulElementWise returns the component-wise product of v and u
+func (v Vec) MulElementWise(u Vec) (Vec, error) {
+	if len(v) != len(u) {
+		return nil, fmt.Errorf("vectors have %d and %d components: %w", len(v), len(u), ErrDimensionMismatch)
+	}
+	result := make(Vec, len(v))
+	for i := range v {
+		result[i] = v[i] * u[i]
+	}
+	return result, nil
+}
+
+// DivElementWise returns the component-wise quotient of v and u, it fails with ErrDivisionByZero if a component of u is zero
+func (v Vec) DivElementWise(u Vec) (Vec, error) {
+	if len(v) != len(u) {
+		return nil, fmt.Errorf("vectors have %d and %d components: %w", len(v), len(u), ErrDimensionMismatch)
+	}
+	result := make(Vec, len(v))
+	for i := range v {
+		if u[i] == 0 {
+			return nil, fmt.Errorf("component %d: %w", i, ErrDivisionByZero)
+		}
+		result[i] = v[i] / u[i]
+	}
+	return result, nil
+}
+
+// PowElementWise returns v with each component raised to the power exp
+func (v Vec) PowElementWise(exp float64) Vec {
+	result := make(Vec, len(v))
+	for i, f := range v {
+		result[i] = math.Pow(f, exp)
+	}
+	return result
+}
+
+// Mat is a matrix attribute value. Its data is always stored row-major: MarshalXML writes each row with
+// RawRowView and every reader fills the data one row after another, so a matrix round-trips without being transposed.
 type Mat mat.Dense
 
 func (m Mat) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
@@ -132,6 +469,150 @@ func (v Vec) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return nil
 }
 
+// UnmarshalXML reads the x, y, z and w attributes of start into v
+func (v *Vec) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var vec Vec
+	for _, a := range start.Attr {
+		i := strings.Index("xyzw", a.Name.Local)
+		if len(a.Name.Local) != 1 || i < 0 {
+			continue
+		}
+		for len(vec) <= i {
+			vec = append(vec, 0)
+		}
+		f, err := parseComponent(a.Value)
+		if err != nil {
+			return numberError(DT_Float, a.Value, err)
+		}
+		vec[i] = f
+	}
+	if size, _, ok := elementDims(start.Name.Local, "float"); ok && size != len(vec) {
+		return fmt.Errorf("%s has %d components: %w", start.Name.Local, len(vec), ErrDimensionMismatch)
+	}
+	*v = vec
+	return d.Skip()
+}
+
+// parseComponent parses a component of a vector or matrix. Vector and matrix elements and the value attribute of
+// FromString are both read with it, so the same text always gives the same value.
+func parseComponent(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// elementDims returns the dimensions in the name of a vector or matrix element, e.g. 3, 3 for float3
+// and 3, 4 for mat3x4. ok is false if name is not prefix followed by its dimensions.
+func elementDims(name, prefix string) (rows, cols int, ok bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return 0, 0, false
+	}
+	r, c, hasCols := strings.Cut(name[len(prefix):], "x")
+	rows, err := strconv.Atoi(r)
+	if err != nil {
+		return 0, 0, false
+	}
+	cols = rows
+	if hasCols {
+		cols, err = strconv.Atoi(c)
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	return rows, cols, true
+}
+
+// GobEncode encodes the matrix using the binary format of mat.Dense
+func (m Mat) GobEncode() ([]byte, error) {
+	M := mat.Dense(m)
+	return M.MarshalBinary()
+}
+
+// GobDecode decodes a matrix encoded by GobEncode
+func (m *Mat) GobDecode(p []byte) error {
+	var M mat.Dense
+	err := M.UnmarshalBinary(p)
+	if err != nil {
+		return err
+	}
+	*m = Mat(M)
+	return nil
+}
+
+// MarshalJSON encodes the matrix as an array of rows
+func (m Mat) MarshalJSON() ([]byte, error) {
+	var (
+		M          = mat.Dense(m)
+		rows, cols = M.Dims()
+		v          = make([][]float64, rows)
+	)
+	for i := range v {
+		v[i] = make([]float64, cols)
+		copy(v[i], M.RawRowView(i))
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a matrix encoded by MarshalJSON
+func (m *Mat) UnmarshalJSON(p []byte) error {
+	var (
+		v    [][]float64
+		data []float64
+	)
+	err := json.Unmarshal(p, &v)
+	if err != nil {
+		return err
+	}
+	if len(v) == 0 || len(v[0]) == 0 {
+		return errors.New("matrix has no values")
+	}
+	for i, row := range v {
+		if len(row) != len(v[0]) {
+			return fmt.Errorf("matrix row %d has %d columns, expected %d: %w", i, len(row), len(v[0]), ErrDimensionMismatch)
+		}
+		data = append(data, row...)
+	}
+	*m = Mat(*mat.NewDense(len(v), len(v[0]), data))
+	return nil
+}
+
+// UnmarshalXML reads each child element of start as a row of the matrix
+func (m *Mat) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var (
+		rows int
+		data []float64
+		cols = -1
+	)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			var row Vec
+			err = d.DecodeElement(&row, &t)
+			if err != nil {
+				return err
+			}
+			if cols != -1 && cols != len(row) {
+				return fmt.Errorf("matrix row %d has %d columns, expected %d: %w", rows, len(row), cols, ErrDimensionMismatch)
+			}
+			cols = len(row)
+			data = append(data, row...)
+			rows++
+
+		case xml.EndElement:
+			if rows == 0 || cols == 0 {
+				return errors.New("matrix has no values")
+			}
+			if r, c, ok := elementDims(start.Name.Local, "mat"); ok && (r != rows || c != cols) {
+				return fmt.Errorf("%s has %d rows and %d columns: %w", start.Name.Local, rows, cols, ErrDimensionMismatch)
+			}
+			*m = Mat(*mat.NewDense(rows, cols, data))
+			return nil
+		}
+	}
+}
+
 type DataType int
 
 const (
@@ -174,7 +655,11 @@ const (
 	DT_Max = iota - 1
 )
 
+// MarshalXMLAttr returns the name of dt as an XML attribute, types unknown to this package return an error
 func (dt *DataType) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if !dt.valid() {
+		return xml.Attr{}, fmt.Errorf("can not marshal %v: %w", *dt, ErrUnknownDataType)
+	}
 	return xml.Attr{
 		Value: dt.String(),
 		Name:  name,
@@ -252,86 +737,877 @@ func (dt DataType) String() string {
 	case DT_TranslatedFSString:
 		return "TranslatedFSString"
 	}
-	return ""
+	return fmt.Sprintf("Unknown(%d)", int(dt))
 }
 
-type NodeAttribute struct {
-	Name  string      `xml:"id,attr"`
-	Type  DataType    `xml:"type,attr"`
-	Value interface{} `xml:"value,attr"`
+// valid reports whether dt is a type known to this package
+func (dt DataType) valid() bool {
+	return DT_None <= dt && dt <= DT_Max
 }
 
-func (na NodeAttribute) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
-	t, _ := na.Type.MarshalXMLAttr(xml.Name{Local: "type"})
-	start.Attr = append(start.Attr,
-		xml.Attr{
-			Name:  xml.Name{Local: "id"},
-			Value: na.Name,
-		},
-		t,
-	)
-	v, MarshalXML2 := na.Value.(XMLMarshaler)
-	v1, MarshalXML := na.Value.(xml.Marshaler)
-	if MarshalXML2 {
-		v.MarshalXML(e, &start)
-	}
-	if !(MarshalXML || MarshalXML2) {
-		start.Attr = append(start.Attr,
-			xml.Attr{
-				Name:  xml.Name{Local: "value"},
-				Value: na.String(),
-			},
-		)
+// AttributeTypeByID returns the DataType of the type ID stored in the attribute tables of LSF and LSB files.
+//
+// The IDs are the values of the DataType enum of LSLib (LSLib/LS/NodeAttribute.cs), which the DataType constants
+// follow in the same order, including DT_Long and DT_Int8 that sit between the older and newer types.
+func AttributeTypeByID(id uint8) (DataType, error) {
+	dt := DataType(id)
+	if !dt.valid() {
+		return DT_None, fmt.Errorf("type ID %d: %w", id, ErrUnknownDataType)
 	}
+	return dt, nil
+}
 
-	e.EncodeToken(start)
-
-	if MarshalXML {
-		e.EncodeElement(v1, xml.StartElement{Name: xml.Name{Local: na.Type.String()}})
+// BinaryID returns the type ID used for dt in LSF and LSB files, it is the inverse of AttributeTypeByID
+func (dt DataType) BinaryID() (uint8, error) {
+	if !dt.valid() {
+		return 0, fmt.Errorf("%v: %w", dt, ErrUnknownDataType)
 	}
-
-	e.EncodeToken(xml.EndElement{
-		Name: start.Name,
-	})
-	return nil
+	return uint8(dt), nil
 }
 
-func (na NodeAttribute) String() string {
-	switch na.Type {
-	case DT_ScratchBuffer:
-		// ScratchBuffer is a special case, as its stored as byte[] and ToString() doesn't really do what we want
-		if value, ok := na.Value.([]byte); ok {
-			return base64.StdEncoding.EncodeToString(value)
-		}
-		return fmt.Sprint(na.Value)
-
-	case DT_Double:
-		v := na.Value.(float64)
-		if na.Value == 0 {
-			na.Value = 0
-		}
-		return strconv.FormatFloat(v, 'f', -1, 64)
-
-	case DT_Float:
-		v := na.Value.(float32)
-		if na.Value == 0 {
-			na.Value = 0
-		}
-		return strconv.FormatFloat(float64(v), 'f', -1, 32)
-
-	default:
-		return fmt.Sprint(na.Value)
+// IsInteger reports whether dt is a signed or unsigned integer type
+func (dt DataType) IsInteger() bool {
+	switch dt {
+	case DT_Byte, DT_Short, DT_UShort, DT_Int, DT_UInt, DT_ULongLong, DT_Long, DT_Int8, DT_Int64:
+		return true
 	}
+	return false
 }
 
-func (na NodeAttribute) GetRows() (int, error) {
-	return na.Type.GetRows()
+// IsFloatingPoint reports whether dt is a scalar floating point type
+func (dt DataType) IsFloatingPoint() bool {
+	return dt == DT_Float || dt == DT_Double
 }
 
-func (dt DataType) GetRows() (int, error) {
+// IsVector reports whether dt is an integer or floating point vector type
+func (dt DataType) IsVector() bool {
 	switch dt {
 	case DT_IVec2, DT_IVec3, DT_IVec4, DT_Vec2, DT_Vec3, DT_Vec4:
-		return 1, nil
+		return true
+	}
+	return false
+}
+
+// IsMatrix reports whether dt is a matrix type
+func (dt DataType) IsMatrix() bool {
+	switch dt {
+	case DT_Mat2, DT_Mat3, DT_Mat3x4, DT_Mat4x3, DT_Mat4:
+		return true
+	}
+	return false
+}
+
+// IsString reports whether dt holds text, including the translated string types
+func (dt DataType) IsString() bool {
+	switch dt {
+	case DT_String, DT_Path, DT_FixedString, DT_LSString, DT_WString, DT_LSWString, DT_TranslatedString, DT_TranslatedFSString:
+		return true
+	}
+	return false
+}
+
+// ID returns the numeric identifier of dt
+func (dt DataType) ID() int {
+	return int(dt)
+}
+
+// ParseDataType returns the DataType named s, the inverse of DataType.String(). Names are matched case-insensitively,
+// surrounding whitespace and byte order marks are ignored.
+func ParseDataType(s string) (DataType, error) {
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '\ufeff'
+	})
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		if strings.EqualFold(dt.String(), s) {
+			return dt, nil
+		}
+	}
+	return DT_None, fmt.Errorf("%q: %w", s, ErrUnknownDataType)
+}
+
+type NodeAttribute struct {
+	Name  string      `xml:"id,attr"`
+	Type  DataType    `xml:"type,attr"`
+	Value interface{} `xml:"value,attr"`
+
+	// ExtraAttrs holds attributes of the LSX attribute element that are not part of the format,
+	// such as those added by third party tools. They are written back after the known attributes.
+	ExtraAttrs []xml.Attr `xml:"-" json:"-"`
+}
+
+// isLSXAttributeAttr reports whether name is an attribute of the LSX attribute element read by NodeAttribute.UnmarshalXML
+func isLSXAttributeAttr(name xml.Name) bool {
+	if name.Space != "" {
+		return false
+	}
+	switch name.Local {
+	case "id", "type", "value", "handle", "version", "arguments":
+		return true
+	}
+	return false
+}
+
+// RegisterGobTypes registers every type that can be stored in NodeAttribute.Value with encoding/gob.
+// It must be called once before NodeAttributes are encoded or decoded with gob.
+func RegisterGobTypes() {
+	for _, v := range []interface{}{
+		int8(0), int16(0), int32(0), int64(0),
+		uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		false, "", []byte(nil),
+		Vec(nil), Ivec(nil), &Mat{},
+		uuid.UUID{},
+		TranslatedString{}, TranslatedFSString{},
+	} {
+		gob.Register(v)
+	}
+}
+
+// MarshalXMLOptions changes how nodes and attributes are written by MarshalXMLWithOpts
+type MarshalXMLOptions struct {
+	// OmitZeroValues leaves out attributes for which NodeAttribute.IsZero is true
+	OmitZeroValues bool
+
+	// HexAttributes lists the names of integer attributes whose value is written in hexadecimal, e.g. "0x1f"
+	HexAttributes map[string]bool
+}
+
+// MarshalXMLWithOpts is MarshalXML, it writes nothing if opts.OmitZeroValues is set and na is zero
+// and writes the value in hexadecimal if na is an integer named in opts.HexAttributes
+func (na NodeAttribute) MarshalXMLWithOpts(e *xml.Encoder, start xml.StartElement, opts MarshalXMLOptions) error {
+	if opts.OmitZeroValues && na.IsZero() {
+		return nil
+	}
+	if opts.HexAttributes[na.Name] && na.Type.IsInteger() {
+		return na.marshalXML(e, start, na.hexString)
+	}
+	return na.MarshalXML(e, start)
+}
+
+// hexString formats an integer value as a hexadecimal number with a 0x prefix, which FromString accepts
+func (na NodeAttribute) hexString() string {
+	v := reflect.ValueOf(na.Value)
+	switch {
+	case v.CanInt() && v.Int() < 0:
+		return "-0x" + strconv.FormatUint(uint64(-v.Int()), 16)
+	case v.CanInt():
+		return "0x" + strconv.FormatInt(v.Int(), 16)
+	case v.CanUint():
+		return "0x" + strconv.FormatUint(v.Uint(), 16)
+	default:
+		return na.String()
+	}
+}
+
+func (na NodeAttribute) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return na.marshalXML(e, start, na.String)
+}
+
+// marshalXML writes na as an attribute element, format returns the value attribute of scalar values
+func (na NodeAttribute) marshalXML(e *xml.Encoder, start xml.StartElement, format func() string) error {
+	t, err := na.Type.MarshalXMLAttr(xml.Name{Local: "type"})
+	if err != nil {
+		return fmt.Errorf("attribute %s: %w", na.Name, err)
+	}
+	start.Attr = append(start.Attr,
+		xml.Attr{
+			Name:  xml.Name{Local: "id"},
+			Value: na.Name,
+		},
+		t,
+	)
+	v, MarshalXML2 := na.Value.(XMLMarshaler)
+	v1, MarshalXML := na.Value.(xml.Marshaler)
+	if tfs, ok := na.Value.(TranslatedFSString); ok {
+		// The MarshalXML promoted from TranslatedString does not add the number of arguments
+		err = tfs.marshalXML(e, &start)
+	} else if MarshalXML2 {
+		err = v.MarshalXML(e, &start)
+	}
+	if err != nil {
+		return fmt.Errorf("attribute %s: %w", na.Name, err)
+	}
+	if !(MarshalXML || MarshalXML2) {
+		start.Attr = append(start.Attr,
+			xml.Attr{
+				Name:  xml.Name{Local: "value"},
+				Value: format(),
+			},
+		)
+	}
+
+	start.Attr = append(start.Attr, na.ExtraAttrs...)
+
+	err = e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	if v, ok := na.Value.(TranslatedFSString); ok {
+		err := v.MarshalXMLArguments(e)
+		if err != nil {
+			return fmt.Errorf("attribute %s: %w", na.Name, err)
+		}
+	}
+
+	if MarshalXML {
+		err = e.EncodeElement(v1, xml.StartElement{Name: xml.Name{Local: na.Type.String()}})
+		if err != nil {
+			return fmt.Errorf("attribute %s: %w", na.Name, err)
+		}
+	}
+
+	return e.EncodeToken(xml.EndElement{
+		Name: start.Name,
+	})
+}
+
+// UnmarshalXML reads an attribute element. Scalar values are read from the value attribute using FromString,
+// vectors and matrices may instead be stored as a child element. An attribute element with neither is read as
+// FromString("").
+func (na *NodeAttribute) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var (
+		value    string
+		hasValue bool
+		hasType  bool
+		err      error
+	)
+	for _, a := range start.Attr {
+		switch a.Name.Local {
+		case "id":
+			na.Name = a.Value
+
+		case "type":
+			hasType = true
+			na.Type, err = ParseDataType(a.Value)
+			if err != nil {
+				return err
+			}
+
+		case "value":
+			value, hasValue = a.Value, true
+		}
+		if !isLSXAttributeAttr(a.Name) {
+			na.ExtraAttrs = append(na.ExtraAttrs, a)
+		}
+	}
+	if !hasType {
+		return fmt.Errorf("attribute %s: %w", na.Name, ErrMissingType)
+	}
+
+	switch na.Type {
+	case DT_TranslatedString:
+		var v TranslatedString
+		err = d.DecodeElement(&v, &start)
+		na.Value = v
+		return err
+
+	case DT_TranslatedFSString:
+		var v TranslatedFSString
+		err = d.DecodeElement(&v, &start)
+		na.Value = v
+		return err
+	}
+
+	if hasValue {
+		err = na.FromString(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch na.Type {
+			case DT_IVec2, DT_IVec3, DT_IVec4:
+				var v Ivec
+				err = d.DecodeElement(&v, &t)
+				na.Value = v
+
+			case DT_Vec2, DT_Vec3, DT_Vec4:
+				var v Vec
+				err = d.DecodeElement(&v, &t)
+				na.Value = v
+
+			case DT_Mat2, DT_Mat3, DT_Mat3x4, DT_Mat4x3, DT_Mat4:
+				v := &Mat{}
+				err = d.DecodeElement(v, &t)
+				na.Value = v
+
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			if !hasValue && na.Value == nil {
+				// The attribute has neither a value attribute nor a child element, read it as an empty value
+				return na.FromString("")
+			}
+			return nil
+		}
+	}
+}
+
+type jsonNodeAttribute struct {
+	Name  string          `json:"id"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// NodeAttributeList is a sequence of attributes read from the attribute child elements of an XML element
+type NodeAttributeList []NodeAttribute
+
+// UnmarshalXML appends each attribute child element of start to l, other child elements are skipped.
+// It can be used to read the attributes of a node element without reading its children.
+func (l *NodeAttributeList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "attribute" {
+				err = d.Skip()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			var attr NodeAttribute
+			err = d.DecodeElement(&attr, &t)
+			if err != nil {
+				return err
+			}
+			*l = append(*l, attr)
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// MarshalJSON encodes na as {"id":…,"type":…,"value":…}. Numbers and booleans are encoded as JSON numbers and booleans,
+// UUIDs and strings as strings, vectors as arrays, matrices as arrays of rows and scratch buffers as base64 strings.
+func (na NodeAttribute) MarshalJSON() ([]byte, error) {
+	var (
+		v   []byte
+		err error
+	)
+	if !na.Type.valid() {
+		return nil, fmt.Errorf("attribute %s: can not marshal %v: %w", na.Name, na.Type, ErrUnknownDataType)
+	}
+	switch na.Type {
+	case DT_Float, DT_Double:
+		// Use the same representation as String, encoding/json writes large and small float32s with excess precision
+		v = []byte(na.String())
+	default:
+		v, err = json.Marshal(na.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(jsonNodeAttribute{na.Name, na.Type.String(), v})
+}
+
+// UnmarshalJSON decodes an attribute encoded by MarshalJSON, scalar values are converted to the Go type of na.Type using FromString
+func (na *NodeAttribute) UnmarshalJSON(p []byte) error {
+	var (
+		v   jsonNodeAttribute
+		err error
+	)
+	err = json.Unmarshal(p, &v)
+	if err != nil {
+		return err
+	}
+	na.Name = v.Name
+	na.Type, err = ParseDataType(v.Type)
+	if err != nil {
+		return err
+	}
+	na.Value = nil
+	if len(v.Value) == 0 || string(v.Value) == "null" {
+		return nil
+	}
+
+	switch na.Type {
+	case DT_TranslatedString:
+		var ts TranslatedString
+		err = json.Unmarshal(v.Value, &ts)
+		na.Value = ts
+		return err
+
+	case DT_TranslatedFSString:
+		var tfs TranslatedFSString
+		err = json.Unmarshal(v.Value, &tfs)
+		na.Value = tfs
+		return err
+
+	case DT_IVec2, DT_IVec3, DT_IVec4:
+		var vec Ivec
+		err = json.Unmarshal(v.Value, &vec)
+		if err != nil {
+			return err
+		}
+		if length, _ := na.GetColumns(); length != len(vec) {
+			return fmt.Errorf("A vector of length %d was expected, got %d", length, len(vec))
+		}
+		na.Value = vec
+		return nil
+
+	case DT_Vec2, DT_Vec3, DT_Vec4:
+		var vec Vec
+		err = json.Unmarshal(v.Value, &vec)
+		if err != nil {
+			return err
+		}
+		if length, _ := na.GetColumns(); length != len(vec) {
+			return fmt.Errorf("A vector of length %d was expected, got %d", length, len(vec))
+		}
+		na.Value = vec
+		return nil
+
+	case DT_Mat2, DT_Mat3, DT_Mat3x4, DT_Mat4x3, DT_Mat4:
+		m := &Mat{}
+		err = json.Unmarshal(v.Value, m)
+		if err != nil {
+			return err
+		}
+		rows, _ := na.GetRows()
+		cols, _ := na.GetColumns()
+		if r, c := (*mat.Dense)(m).Dims(); r != rows || c != cols {
+			return fmt.Errorf("A %dx%d matrix was expected, got %dx%d", rows, cols, r, c)
+		}
+		na.Value = m
+		return nil
+	}
+
+	// Strings, UUIDs and scratch buffers are JSON strings, numbers and booleans are parsed from their JSON text
+	var str string
+	if v.Value[0] == '"' {
+		err = json.Unmarshal(v.Value, &str)
+		if err != nil {
+			return err
+		}
+	} else {
+		str = string(v.Value)
+	}
+	return na.FromString(str)
+}
+
+func (na NodeAttribute) String() string {
+	switch na.Type {
+	case DT_ScratchBuffer:
+		// ScratchBuffer is a special case, as its stored as byte[] and ToString() doesn't really do what we want
+		if value, ok := na.Value.([]byte); ok {
+			return base64.StdEncoding.EncodeToString(value)
+		}
+		return fmt.Sprint(na.Value)
+
+	case DT_Byte:
+		if value, ok := na.Value.(uint8); ok {
+			return strconv.FormatUint(uint64(value), 10)
+		}
+		return fmt.Sprint(na.Value)
+
+	case DT_UUID:
+		if value, ok := na.Value.(uuid.UUID); ok {
+			return value.String()
+		}
+		return fmt.Sprint(na.Value)
+
+	case DT_Bool:
+		// LSX files capitalize booleans
+		if value, ok := na.Value.(bool); ok {
+			if value {
+				return "True"
+			}
+			return "False"
+		}
+		return fmt.Sprint(na.Value)
+
+	case DT_Double:
+		if value, ok := na.Value.(float64); ok {
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		return fmt.Sprint(na.Value)
+
+	case DT_Float:
+		if value, ok := na.Value.(float32); ok {
+			return strconv.FormatFloat(float64(value), 'f', -1, 32)
+		}
+		return fmt.Sprint(na.Value)
+
+	default:
+		return fmt.Sprint(na.Value)
+	}
+}
+
+// CanonicalString returns the value of na in a normalized form so that equal values always produce the same
+// text: floats use their shortest representation without negative zero, UUIDs are lowercase with dashes,
+// booleans are written as in LSX files and matrices are written row by row. Other values are formatted by String.
+func (na NodeAttribute) CanonicalString() string {
+	switch v := na.Value.(type) {
+	case float32:
+		return canonicalFloat(float64(v), 32)
+	case float64:
+		return canonicalFloat(v, 64)
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case uuid.UUID:
+		return v.String()
+	case Vec:
+		return canonicalFloats(v)
+	case *Mat:
+		if v == nil || (*mat.Dense)(v).IsEmpty() {
+			return ""
+		}
+		M := (*mat.Dense)(v)
+		rows, cols := M.Dims()
+		floats := make([]float64, 0, rows*cols)
+		for i := 0; i < rows; i++ {
+			floats = append(floats, M.RawRowView(i)...)
+		}
+		return canonicalFloats(floats)
+	}
+	return na.String()
+}
+
+// AsString converts the value of na to a string. String formats an attribute for display and the value
+// attribute of LSX files, AsString is meant for code that needs the value itself as text: numbers are written
+// with full precision, UUIDs in their canonical form, translated strings as their handle and scratch buffers
+// as base64. Vectors and matrices are written as by CanonicalString, an unset value is the empty string.
+func (na NodeAttribute) AsString() string {
+	switch v := na.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case uuid.UUID:
+		return v.String()
+	case TranslatedString:
+		return v.Handle
+	case TranslatedFSString:
+		return v.Handle
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case Ivec, Vec, *Mat:
+		return na.CanonicalString()
+	}
+	return fmt.Sprint(na.Value)
+}
+
+func canonicalFloat(f float64, bitSize int) string {
+	if f == 0 {
+		// Negative zero
+		f = 0
+	}
+	return strconv.FormatFloat(f, 'f', -1, bitSize)
+}
+
+// canonicalFloats formats the components of a vector or matrix, which are stored as float32
+func canonicalFloats(floats []float64) string {
+	s := make([]string, len(floats))
+	for i, f := range floats {
+		s[i] = canonicalFloat(f, 32)
+	}
+	return strings.Join(s, " ")
+}
+
+// Equal reports whether na and other have the same name, type and value.
+// Matrices are equal if they have the same dimensions and elements, other values are compared deeply.
+func (na NodeAttribute) Equal(other NodeAttribute) bool {
+	if na.Name != other.Name || na.Type != other.Type {
+		return false
+	}
+	if a, ok := na.Value.(*Mat); ok {
+		b, ok := other.Value.(*Mat)
+		if !ok || a == nil || b == nil {
+			return ok && a == b
+		}
+		return matEqual((*mat.Dense)(a), (*mat.Dense)(b))
+	}
+	return reflect.DeepEqual(na.Value, other.Value)
+}
+
+// equalWithin is Equal, except that float, vector and matrix components may differ by up to epsilon
+func (na NodeAttribute) equalWithin(other NodeAttribute, epsilon float64) bool {
+	if epsilon <= 0 || na.Name != other.Name || na.Type != other.Type {
+		return na.Equal(other)
+	}
+	within := func(a, b float64) bool {
+		return math.Abs(a-b) <= epsilon
+	}
+	switch a := na.Value.(type) {
+	case float32:
+		b, ok := other.Value.(float32)
+		return ok && within(float64(a), float64(b))
+	case float64:
+		b, ok := other.Value.(float64)
+		return ok && within(a, b)
+	case Vec:
+		b, ok := other.Value.(Vec)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !within(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case *Mat:
+		b, ok := other.Value.(*Mat)
+		if !ok || a == nil || b == nil || (*mat.Dense)(a).IsEmpty() || (*mat.Dense)(b).IsEmpty() {
+			return na.Equal(other)
+		}
+		return mat.EqualApprox((*mat.Dense)(a), (*mat.Dense)(b), epsilon)
+	}
+	return na.Equal(other)
+}
+
+// matEqual is mat.Equal that also accepts empty matrices, which panic when their dimensions are read
+func matEqual(a, b *mat.Dense) bool {
+	if a.IsEmpty() || b.IsEmpty() {
+		return a.IsEmpty() && b.IsEmpty()
+	}
+	return mat.Equal(a, b)
+}
+
+// IsZero reports whether the value of na is unset or the zero value of its type.
+// Vectors and matrices are zero if every component is zero, translated strings if they have no handle, value or arguments.
+func (na NodeAttribute) IsZero() bool {
+	switch v := na.Value.(type) {
+	case nil:
+		return true
+	case Ivec:
+		for _, c := range v {
+			if c != 0 {
+				return false
+			}
+		}
+		return true
+	case Vec:
+		for _, c := range v {
+			if c != 0 {
+				return false
+			}
+		}
+		return true
+	case *Mat:
+		if v == nil || (*mat.Dense)(v).IsEmpty() {
+			return true
+		}
+		rows, cols := (*mat.Dense)(v).Dims()
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				if (*mat.Dense)(v).At(i, j) != 0 {
+					return false
+				}
+			}
+		}
+		return true
+	case []byte:
+		return len(v) == 0
+	case TranslatedString:
+		return v.Value == "" && v.Handle == ""
+	case TranslatedFSString:
+		return v.Value == "" && v.Handle == "" && len(v.Arguments) == 0
+	}
+	return reflect.ValueOf(na.Value).IsZero()
+}
+
+// Clone returns a copy of na that shares no memory with it
+func (na NodeAttribute) Clone() NodeAttribute {
+	clone := na
+	if na.ExtraAttrs != nil {
+		clone.ExtraAttrs = append([]xml.Attr(nil), na.ExtraAttrs...)
+	}
+	switch v := na.Value.(type) {
+	case Vec:
+		clone.Value = append(Vec(nil), v...)
+	case Ivec:
+		clone.Value = append(Ivec(nil), v...)
+	case []byte:
+		clone.Value = append([]byte(nil), v...)
+	case *Mat:
+		if v != nil {
+			clone.Value = (*Mat)(mat.DenseCopyOf((*mat.Dense)(v)))
+		}
+	case TranslatedFSString:
+		clone.Value = v.clone()
+	}
+	return clone
+}
+
+func (tfs TranslatedFSString) clone() TranslatedFSString {
+	if tfs.Arguments == nil {
+		return tfs
+	}
+	arguments := make([]TranslatedFSStringArgument, len(tfs.Arguments))
+	for i, arg := range tfs.Arguments {
+		arguments[i] = arg
+		arguments[i].String = arg.String.clone()
+	}
+	tfs.Arguments = arguments
+	return tfs
+}
+
+// Validate returns an error if the Go type of the value of na is not the one used for na.Type,
+// or if a vector or matrix does not have the dimensions of na.Type
+func (na NodeAttribute) Validate() error {
+	if na.Type == DT_None {
+		if na.Value != nil {
+			return fmt.Errorf("attribute %s: %v can not have a value, got %T", na.Name, na.Type, na.Value)
+		}
+		return nil
+	}
+	goType := na.Type.goType()
+	if goType == nil {
+		return fmt.Errorf("attribute %s: unknown data type %v", na.Name, na.Type)
+	}
+	if reflect.TypeOf(na.Value) != goType {
+		return fmt.Errorf("attribute %s: %v must hold a %v, got %T", na.Name, na.Type, goType, na.Value)
+	}
+
+	cols, _ := na.GetColumns()
+	switch v := na.Value.(type) {
+	case Ivec:
+		if len(v) != cols {
+			return fmt.Errorf("attribute %s: %v must have %d components, got %d", na.Name, na.Type, cols, len(v))
+		}
+	case Vec:
+		if len(v) != cols {
+			return fmt.Errorf("attribute %s: %v must have %d components, got %d", na.Name, na.Type, cols, len(v))
+		}
+	case *Mat:
+		rows, _ := na.GetRows()
+		if v == nil {
+			return fmt.Errorf("attribute %s: %v is nil", na.Name, na.Type)
+		}
+		if r, c := (*mat.Dense)(v).Dims(); r != rows || c != cols {
+			return fmt.Errorf("attribute %s: %v must be %dx%d, got %dx%d", na.Name, na.Type, rows, cols, r, c)
+		}
+	}
+	return nil
+}
+
+// TypeInfo returns both the name and the numeric identifier of the attributes type
+func (na NodeAttribute) TypeInfo() (name string, id int) {
+	return na.Type.String(), na.Type.ID()
+}
+
+// GetInt64 returns the value of an integer attribute as an int64
+func (na NodeAttribute) GetInt64() (int64, error) {
+	switch v := na.Value.(type) {
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), nil
+		}
+		return 0, fmt.Errorf("value %d of attribute %s overflows int64", v, na.Name)
+	}
+	return 0, fmt.Errorf("cannot read %v attribute %s as int64", na.Type, na.Name)
+}
+
+// GetUint64 returns the value of a non-negative integer attribute as a uint64
+func (na NodeAttribute) GetUint64() (uint64, error) {
+	switch v := na.Value.(type) {
+	case uint8:
+		return uint64(v), nil
+	case uint16:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	}
+	v, err := na.GetInt64()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read %v attribute %s as uint64", na.Type, na.Name)
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("value %d of attribute %s is negative", v, na.Name)
+	}
+	return uint64(v), nil
+}
+
+// GetFloat64 returns the value of a numeric attribute as a float64
+func (na NodeAttribute) GetFloat64() (float64, error) {
+	switch v := na.Value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case uint64:
+		return float64(v), nil
+	}
+	v, err := na.GetInt64()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read %v attribute %s as float64", na.Type, na.Name)
+	}
+	return float64(v), nil
+}
+
+// GetString returns the value of a string attribute, or the value of a translated string
+func (na NodeAttribute) GetString() (string, error) {
+	switch v := na.Value.(type) {
+	case string:
+		return v, nil
+	case TranslatedString:
+		return v.Value, nil
+	case TranslatedFSString:
+		return v.Value, nil
+	}
+	return "", fmt.Errorf("cannot read %v attribute %s as string", na.Type, na.Name)
+}
+
+// GetUUID returns the value of a DT_UUID attribute
+func (na NodeAttribute) GetUUID() (uuid.UUID, error) {
+	if v, ok := na.Value.(uuid.UUID); ok {
+		return v, nil
+	}
+	return uuid.Nil, fmt.Errorf("cannot read %v attribute %s as uuid", na.Type, na.Name)
+}
+
+func (na NodeAttribute) GetRows() (int, error) {
+	return na.Type.GetRows()
+}
+
+func (dt DataType) GetRows() (int, error) {
+	switch dt {
+	case DT_IVec2, DT_IVec3, DT_IVec4, DT_Vec2, DT_Vec3, DT_Vec4:
+		return 1, nil
 
 	case DT_Mat2:
 		return 2, nil
@@ -343,7 +1619,7 @@ func (dt DataType) GetRows() (int, error) {
 		return 4, nil
 
 	default:
-		return 0, errors.New("Data type does not have rows")
+		return 0, fmt.Errorf("%v does not have rows: %w", dt, ErrDimensionMismatch)
 	}
 }
 
@@ -363,19 +1639,203 @@ func (dt DataType) GetColumns() (int, error) {
 		return 4, nil
 
 	default:
-		return 0, errors.New("Data type does not have columns")
+		return 0, fmt.Errorf("%v does not have columns: %w", dt, ErrDimensionMismatch)
 	}
 }
 
+// goType returns the Go type stored in NodeAttribute.Value for dt
+func (dt DataType) goType() reflect.Type {
+	var v interface{}
+	switch dt {
+	case DT_Byte:
+		v = uint8(0)
+	case DT_Short:
+		v = int16(0)
+	case DT_UShort:
+		v = uint16(0)
+	case DT_Int:
+		v = int32(0)
+	case DT_UInt:
+		v = uint32(0)
+	case DT_Float:
+		v = float32(0)
+	case DT_Double:
+		v = float64(0)
+	case DT_IVec2, DT_IVec3, DT_IVec4:
+		v = Ivec(nil)
+	case DT_Vec2, DT_Vec3, DT_Vec4:
+		v = Vec(nil)
+	case DT_Mat2, DT_Mat3, DT_Mat3x4, DT_Mat4x3, DT_Mat4:
+		v = &Mat{}
+	case DT_Bool:
+		v = false
+	case DT_String, DT_Path, DT_FixedString, DT_LSString, DT_WString, DT_LSWString:
+		v = ""
+	case DT_TranslatedString:
+		v = TranslatedString{}
+	case DT_TranslatedFSString:
+		v = TranslatedFSString{}
+	case DT_ULongLong:
+		v = uint64(0)
+	case DT_ScratchBuffer:
+		v = []byte(nil)
+	case DT_Long, DT_Int64:
+		v = int64(0)
+	case DT_Int8:
+		v = int8(0)
+	case DT_UUID:
+		v = uuid.UUID{}
+	default:
+		return nil
+	}
+	return reflect.TypeOf(v)
+}
+
+// NewNodeAttribute returns an attribute named name holding the zero value of dt. Vectors and matrices are filled
+// with zeros in the dimensions of dt.
+func NewNodeAttribute(name string, dt DataType) (NodeAttribute, error) {
+	na := NodeAttribute{Name: name, Type: dt}
+	goType := dt.goType()
+	if goType == nil {
+		return na, fmt.Errorf("attribute %s: %v has no default value: %w", name, dt, ErrUnknownDataType)
+	}
+	cols, _ := dt.GetColumns()
+	switch dt {
+	case DT_IVec2, DT_IVec3, DT_IVec4:
+		na.Value = make(Ivec, cols)
+	case DT_Vec2, DT_Vec3, DT_Vec4:
+		na.Value = make(Vec, cols)
+	case DT_Mat2, DT_Mat3, DT_Mat3x4, DT_Mat4x3, DT_Mat4:
+		rows, _ := dt.GetRows()
+		m := Mat(*mat.NewDense(rows, cols, nil))
+		na.Value = &m
+	default:
+		na.Value = reflect.Zero(goType).Interface()
+	}
+	return na, nil
+}
+
+// IsNumeric reports whether na holds a scalar integer or floating point value
 func (na NodeAttribute) IsNumeric() bool {
-	switch na.Type {
-	case DT_Byte, DT_Short, DT_Int, DT_UInt, DT_Float, DT_Double, DT_ULongLong, DT_Long, DT_Int8:
+	return na.Type.IsInteger() || na.Type.IsFloatingPoint()
+}
+
+// SetValue sets the value of na to v after checking that it can be stored as na.Type. Numbers of any Go type are
+// converted to the type used for na.Type if integers keep their value, other values must have the Go type used for
+// na.Type or one convertible to it. Vectors and matrices that do not have the dimensions of na.Type are rejected
+// with ErrDimensionMismatch.
+func (na *NodeAttribute) SetValue(v interface{}) error {
+	goType := na.Type.goType()
+	if goType == nil {
+		return fmt.Errorf("attribute %s: %v can not hold a value: %w", na.Name, na.Type, ErrUnknownDataType)
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return fmt.Errorf("attribute %s: nil can not be stored as %v", na.Name, na.Type)
+	}
+	value, err := convertValue(rv, goType)
+	if err != nil {
+		return fmt.Errorf("attribute %s: can not be stored as %v: %w", na.Name, na.Type, err)
+	}
+	value = value.Convert(goType)
+
+	switch value := value.Interface().(type) {
+	case Ivec:
+		err := na.checkDimensions(1, len(value))
+		if err != nil {
+			return err
+		}
+	case Vec:
+		err := na.checkDimensions(1, len(value))
+		if err != nil {
+			return err
+		}
+	case *Mat:
+		if value == nil || (*mat.Dense)(value).IsEmpty() {
+			return fmt.Errorf("attribute %s: matrix is empty: %w", na.Name, ErrDimensionMismatch)
+		}
+		err := na.checkDimensions((*mat.Dense)(value).Dims())
+		if err != nil {
+			return err
+		}
+	}
+	na.Value = value.Interface()
+	return nil
+}
+
+// fitsInteger reports whether the number v can be converted to the integer type t without changing its value,
+// it is true if t is not an integer type
+func fitsInteger(v reflect.Value, t reflect.Type) bool {
+	var (
+		target   = reflect.New(t).Elem()
+		unsigned = reflect.Uint <= t.Kind() && t.Kind() <= reflect.Uintptr
+		signed   = reflect.Int <= t.Kind() && t.Kind() <= reflect.Int64
+	)
+	if !signed && !unsigned {
 		return true
-	default:
-		return false
 	}
+	switch {
+	case v.CanInt():
+		i := v.Int()
+		if unsigned {
+			return i >= 0 && !target.OverflowUint(uint64(i))
+		}
+		return !target.OverflowInt(i)
+
+	case v.CanUint():
+		u := v.Uint()
+		if signed {
+			return u <= math.MaxInt64 && !target.OverflowInt(int64(u))
+		}
+		return !target.OverflowUint(u)
+
+	case v.CanFloat():
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return false
+		}
+		if signed {
+			return f >= math.MinInt64 && f < math.MaxInt64 && !target.OverflowInt(int64(f))
+		}
+		return f >= 0 && f < math.MaxUint64 && !target.OverflowUint(uint64(f))
+	}
+	return false
 }
 
+// checkDimensions returns ErrDimensionMismatch if na.Type does not have rows and cols
+func (na NodeAttribute) checkDimensions(rows, cols int) error {
+	r, err := na.GetRows()
+	if err != nil {
+		return err
+	}
+	c, err := na.GetColumns()
+	if err != nil {
+		return err
+	}
+	if r != rows || c != cols {
+		return fmt.Errorf("attribute %s: %v value has %d rows and %d columns, expected %d and %d: %w", na.Name, na.Type, rows, cols, r, c, ErrDimensionMismatch)
+	}
+	return nil
+}
+
+// numberError wraps the syntax error of parsing str as a number in ErrNotNumeric, range errors are returned as they are
+func numberError(dt DataType, str string, err error) error {
+	if errors.Is(err, strconv.ErrSyntax) {
+		return fmt.Errorf("%v value %q: %w", dt, str, ErrNotNumeric)
+	}
+	return err
+}
+
+// parseUint is strconv.ParseUint with base 0 that also accepts a leading plus sign, as strconv.ParseInt does
+func parseUint(s string, bitSize int) (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(s, "+"), 0, bitSize)
+}
+
+// FromString sets the value of na by parsing str according to na.Type.
+//
+// Integers may have a leading sign and a 0x, 0o or 0b prefix for hexadecimal, octal or binary values
+// ("5", "+5", "+0x10", "-0x10"), a leading 0 also denotes an octal value. A negative sign is rejected for
+// unsigned types. An empty string is read as 0 for numeric types.
 func (na *NodeAttribute) FromString(str string) error {
 	if na.IsNumeric() {
 		// Workaround: Some XML files use empty strings, instead of "0" for zero values.
@@ -394,95 +1854,126 @@ func (na *NodeAttribute) FromString(str string) error {
 		// This is a null type, cannot have a value
 
 	case DT_Byte:
-		na.Value = []byte(str)
+		var v uint64
+		v, err = parseUint(str, 8)
+		if err != nil {
+			return numberError(na.Type, str, err)
+		}
+		na.Value = uint8(v)
 
 	case DT_Short:
-
-		na.Value, err = strconv.ParseInt(str, 0, 16)
+		var v int64
+		v, err = strconv.ParseInt(str, 0, 16)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
+		na.Value = int16(v)
 
 	case DT_UShort:
-		na.Value, err = strconv.ParseUint(str, 0, 16)
+		var v uint64
+		v, err = parseUint(str, 16)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
+		na.Value = uint16(v)
 
 	case DT_Int:
-		na.Value, err = strconv.ParseInt(str, 0, 32)
+		var v int64
+		v, err = strconv.ParseInt(str, 0, 32)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
+		na.Value = int32(v)
 
 	case DT_UInt:
-		na.Value, err = strconv.ParseUint(str, 0, 16)
+		var v uint64
+		v, err = parseUint(str, 32)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
+		na.Value = uint32(v)
 
 	case DT_Float:
-		na.Value, err = strconv.ParseFloat(str, 32)
+		var v float64
+		v, err = strconv.ParseFloat(str, 32)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
+		na.Value = float32(v)
 
 	case DT_Double:
 		na.Value, err = strconv.ParseFloat(str, 64)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
 
 	case DT_IVec2, DT_IVec3, DT_IVec4:
 
-		nums := strings.Split(str, ".")
+		nums := strings.Fields(str)
 		length, err := na.GetColumns()
 		if err != nil {
 			return err
 		}
 		if length != len(nums) {
-			return fmt.Errorf("A vector of length %d was expected, got %d", length, len(nums))
+			return fmt.Errorf("%v value %q has %d components, expected %d: %w", na.Type, str, len(nums), length, ErrDimensionMismatch)
 		}
 
-		vec := make([]int, length)
+		vec := make(Ivec, length)
 		for i, v := range nums {
 			var n int64
-			n, err = strconv.ParseInt(v, 0, 64)
-			vec[i] = int(n)
+			n, err = strconv.ParseInt(v, 0, 32)
 			if err != nil {
-				return err
+				return numberError(na.Type, v, err)
 			}
+			vec[i] = int(n)
 		}
 
 		na.Value = vec
 
 	case DT_Vec2, DT_Vec3, DT_Vec4:
-		nums := strings.Split(str, ".")
+		nums := strings.Fields(str)
 		length, err := na.GetColumns()
 		if err != nil {
 			return err
 		}
 		if length != len(nums) {
-			return fmt.Errorf("A vector of length %d was expected, got %d", length, len(nums))
+			return fmt.Errorf("%v value %q has %d components, expected %d: %w", na.Type, str, len(nums), length, ErrDimensionMismatch)
 		}
 
-		vec := make([]float64, length)
+		vec := make(Vec, length)
 		for i, v := range nums {
-			vec[i], err = strconv.ParseFloat(v, 64)
+			vec[i], err = parseComponent(v)
 			if err != nil {
-				return err
+				return numberError(na.Type, v, err)
 			}
 		}
 
 		na.Value = vec
 
 	case DT_Mat2, DT_Mat3, DT_Mat3x4, DT_Mat4x3, DT_Mat4:
-		// var mat = Matrix.Parse(str);
-		// if (mat.cols != na.GetColumns() || mat.rows != na.GetRows()){
-		//     return errors.New("Invalid column/row count for matrix");
-		// }
-		// value = mat;
-		return errors.New("not implemented")
+		nums := strings.Fields(str)
+		rows, err := na.GetRows()
+		if err != nil {
+			return err
+		}
+		cols, err := na.GetColumns()
+		if err != nil {
+			return err
+		}
+		if rows*cols != len(nums) {
+			return fmt.Errorf("%v value %q has %d components, expected %d: %w", na.Type, str, len(nums), rows*cols, ErrDimensionMismatch)
+		}
+
+		// the components are in row-major order, the order of CanonicalString and of the rows written by MarshalXML
+		data := make([]float64, len(nums))
+		for i, v := range nums {
+			data[i], err = parseComponent(v)
+			if err != nil {
+				return numberError(na.Type, v, err)
+			}
+		}
+		m := Mat(*mat.NewDense(rows, cols, data))
+		na.Value = &m
 
 	case DT_Bool:
 		na.Value, err = strconv.ParseBool(str)
@@ -494,23 +1985,24 @@ func (na *NodeAttribute) FromString(str string) error {
 		na.Value = str
 
 	case DT_TranslatedString:
-		// // We'll only set the value part of the translated string, not the TranslatedStringKey / Handle part
-		// // That can be changed separately via attribute.Value.Handle
-		// if (value == null)
-		//     value = new TranslatedString();
-
-		// ((TranslatedString)value).Value = str;
+		// We'll only set the value part of the translated string, not the TranslatedStringKey / Handle part
+		// That can be changed separately via attribute.Value.Handle
+		ts, _ := na.Value.(TranslatedString)
+		ts.Value = str
+		na.Value = ts
 
 	case DT_TranslatedFSString:
-		// // We'll only set the value part of the translated string, not the TranslatedStringKey / Handle part
-		// // That can be changed separately via attribute.Value.Handle
-		// if (value == null)
-		//     value = new TranslatedFSString();
-
-		// ((TranslatedFSString)value).Value = str;
+		// We'll only set the value part of the translated string, not the TranslatedStringKey / Handle part
+		// That can be changed separately via attribute.Value.Handle
+		tfs, _ := na.Value.(TranslatedFSString)
+		tfs.Value = str
+		na.Value = tfs
 
 	case DT_ULongLong:
-		na.Value, err = strconv.ParseUint(str, 10, 64)
+		na.Value, err = parseUint(str, 64)
+		if err != nil {
+			return numberError(na.Type, str, err)
+		}
 
 	case DT_ScratchBuffer:
 		na.Value, err = base64.StdEncoding.DecodeString(str)
@@ -519,16 +2011,18 @@ func (na *NodeAttribute) FromString(str string) error {
 		}
 
 	case DT_Long, DT_Int64:
-		na.Value, err = strconv.ParseInt(str, 10, 64)
+		na.Value, err = strconv.ParseInt(str, 0, 64)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
 
 	case DT_Int8:
-		na.Value, err = strconv.ParseInt(str, 10, 8)
+		var v int64
+		v, err = strconv.ParseInt(str, 0, 8)
 		if err != nil {
-			return err
+			return numberError(na.Type, str, err)
 		}
+		na.Value = int8(v)
 
 	case DT_UUID:
 		na.Value, err = uuid.Parse(str)
@@ -538,7 +2032,7 @@ func (na *NodeAttribute) FromString(str string) error {
 
 	default:
 		// This should not happen!
-		return fmt.Errorf("FromString() not implemented for type %v", na.Type)
+		return fmt.Errorf("FromString() not implemented for type %v: %w", na.Type, ErrUnknownDataType)
 	}
 	return nil
 }
@@ -0,0 +1,1013 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTranslatedStringLSXRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		dt    DataType
+	}{
+		{"handle only", TranslatedString{Handle: "h1", Version: 3}, DT_TranslatedString},
+		{"with value", TranslatedString{Handle: "h2", Value: "Hello", Version: 1}, DT_TranslatedString},
+		{"fs with value", TranslatedFSString{TranslatedString: TranslatedString{Handle: "h3", Value: "Hi [1]"}}, DT_TranslatedFSString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRegion("Region")
+			root.Attributes = []NodeAttribute{{Name: "Text", Type: tt.dt, Value: tt.value}}
+			res := &Resource{Regions: []*Node{root}}
+
+			var buf bytes.Buffer
+			if err := WriteLSX(&buf, res); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadLSX(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			attr, ok := got.Regions[0].Attribute("Text")
+			if !ok {
+				t.Fatal("attribute Text is missing")
+			}
+			if !attr.Equal(root.Attributes[0]) {
+				t.Errorf("got %#v, want %#v", attr.Value, tt.value)
+			}
+			if !res.Equal(got) {
+				t.Error("Resource.Equal is false after the round-trip")
+			}
+		})
+	}
+}
+
+func TestParseDataType(t *testing.T) {
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		for _, s := range []string{dt.String(), strings.ToUpper(dt.String()), " " + strings.ToLower(dt.String()) + "\n"} {
+			got, err := ParseDataType(s)
+			if err != nil || got != dt {
+				t.Errorf("ParseDataType(%q) = %v, %v, want %v", s, got, err, dt)
+			}
+		}
+	}
+
+	for _, s := range []string{"", "int33", "1", "DT_Int"} {
+		got, err := ParseDataType(s)
+		if !errors.Is(err, ErrUnknownDataType) || got != DT_None {
+			t.Errorf("ParseDataType(%q) = %v, %v, want DT_None, ErrUnknownDataType", s, got, err)
+		}
+	}
+}
+
+func TestFromStringVectors(t *testing.T) {
+	tests := []struct {
+		dt      DataType
+		in      string
+		want    interface{}
+		wantErr error
+	}{
+		{DT_IVec3, "1 2 3", Ivec{1, 2, 3}, nil},
+		{DT_IVec2, "  -1   0x10 ", Ivec{-1, 16}, nil},
+		{DT_Vec4, "0.1 0.2 0.3 0.4", Vec{0.1, 0.2, 0.3, 0.4}, nil},
+		{DT_Vec3, "1.5\t2.5  3.5", Vec{1.5, 2.5, 3.5}, nil},
+		{DT_Vec3, "1.5.2.5.3.5", nil, ErrDimensionMismatch},
+		{DT_IVec4, "1 2 3", nil, ErrDimensionMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dt.String()+" "+tt.in, func(t *testing.T) {
+			na := NodeAttribute{Name: "V", Type: tt.dt}
+			err := na.FromString(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !na.Equal(NodeAttribute{Name: "V", Type: tt.dt, Value: tt.want}) {
+				t.Errorf("got %#v, want %#v", na.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDataTypeSloppy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    DataType
+		wantErr bool
+	}{
+		{" int32 ", DT_Int, false},
+		{"\ufeffint32", DT_Int, false},
+		{"\tFixedString\r\n", DT_FixedString, false},
+		{"in t32", DT_None, true},
+		{"int32x", DT_None, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDataType(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseDataType(%q) = %v, %v, want %v, error: %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestByteRoundTrip(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"0", "0", false},
+		{"200", "200", false},
+		{"255", "255", false},
+		{"0xff", "255", false},
+		{"256", "", true},
+		{"-1", "", true},
+	}
+	for _, tt := range tests {
+		na := NodeAttribute{Name: "B", Type: DT_Byte}
+		err := na.FromString(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FromString(%q) error %v, want error: %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if _, ok := na.Value.(uint8); !ok {
+			t.Errorf("FromString(%q) stored %T, want uint8", tt.in, na.Value)
+		}
+		if got := na.String(); got != tt.want {
+			t.Errorf("FromString(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNodeAttributeUnmarshalXML(t *testing.T) {
+	m := Mat(*mat.NewDense(3, 4, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}))
+	tests := []struct {
+		name    string
+		in      string
+		want    NodeAttribute
+		wantErr error
+	}{
+		{"scalar", `<attribute id="A" type="int32" value="-7"/>`, NodeAttribute{Name: "A", Type: DT_Int, Value: int32(-7)}, nil},
+		{"vector value", `<attribute id="V" type="fvec3" value="1 2.5 3"/>`, NodeAttribute{Name: "V", Type: DT_Vec3, Value: Vec{1, 2.5, 3}}, nil},
+		{"vector element", `<attribute id="V" type="fvec3"><float3 x="1" y="2.5" z="3"/></attribute>`, NodeAttribute{Name: "V", Type: DT_Vec3, Value: Vec{1, 2.5, 3}}, nil},
+		{"matrix element", `<attribute id="M" type="mat3x4"><mat3x4><float4 x="1" y="2" z="3" w="4"/><float4 x="5" y="6" z="7" w="8"/><float4 x="9" y="10" z="11" w="12"/></mat3x4></attribute>`, NodeAttribute{Name: "M", Type: DT_Mat3x4, Value: &m}, nil},
+		{"translated string", `<attribute id="T" type="TranslatedString" handle="h1" version="2"/>`, NodeAttribute{Name: "T", Type: DT_TranslatedString, Value: TranslatedString{Handle: "h1", Version: 2}}, nil},
+		{"ragged matrix", `<attribute id="M" type="mat3x4"><mat3x4><float4 x="1" y="2" z="3" w="4"/><float3 x="5" y="6" z="7"/></mat3x4></attribute>`, NodeAttribute{}, ErrDimensionMismatch},
+		{"missing type", `<attribute id="A" value="1"/>`, NodeAttribute{}, ErrMissingType},
+		{"unknown type", `<attribute id="A" type="int33" value="1"/>`, NodeAttribute{}, ErrUnknownDataType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got NodeAttribute
+			err := xml.Unmarshal([]byte(tt.in), &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !got.Equal(tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeAttributeUnmarshalXMLMatchesFromString(t *testing.T) {
+	tests := []struct {
+		name string
+		dt   DataType
+		// value is passed to FromString, element is the attribute element that must read the same value
+		value   string
+		element string
+	}{
+		{"int32 without value", DT_Int, "", `<attribute id="A" type="int32"/>`},
+		{"double without value", DT_Double, "", `<attribute id="A" type="double"/>`},
+		{"string without value", DT_LSString, "", `<attribute id="A" type="LSString"/>`},
+		{"bool without value", DT_Bool, "", `<attribute id="A" type="bool"/>`},
+		{"uuid without value", DT_UUID, "", `<attribute id="A" type="guid"/>`},
+		{"vector without value", DT_Vec3, "", `<attribute id="A" type="fvec3"/>`},
+		{"vector value", DT_Vec3, "0.1 0.2 0.3", `<attribute id="A" type="fvec3" value="0.1 0.2 0.3"/>`},
+		{"vector element", DT_Vec3, "0.1 0.2 0.3", `<attribute id="A" type="fvec3"><float3 x="0.1" y="0.2" z="0.3"/></attribute>`},
+		{"matrix element", DT_Mat2, "0.1 0.2 0.3 0.4", `<attribute id="A" type="mat2x2"><mat2><float2 x="0.1" y="0.2"/><float2 x="0.3" y="0.4"/></mat2></attribute>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := NodeAttribute{Name: "A", Type: tt.dt}
+			wantErr := want.FromString(tt.value)
+			var got NodeAttribute
+			err := xml.Unmarshal([]byte(tt.element), &got)
+			if (err != nil) != (wantErr != nil) {
+				t.Fatalf("got error %v, FromString returned %v", err, wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got.Value, want.Value) {
+				t.Errorf("got %#v, FromString read %#v", got.Value, want.Value)
+			}
+		})
+	}
+}
+
+// failingWriter accepts limit bytes and then fails every write
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, io.ErrShortWrite
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestNodeAttributeMarshalXMLErrors(t *testing.T) {
+	var args []TranslatedFSStringArgument
+	for i := 0; i < 200; i++ {
+		args = append(args, TranslatedFSStringArgument{Key: fmt.Sprint("key", i), Value: "value", String: TranslatedFSString{TranslatedString: TranslatedString{Handle: "h"}}})
+	}
+	tests := []struct {
+		name    string
+		attr    NodeAttribute
+		w       io.Writer
+		wantErr error
+	}{
+		{"vector too big", NodeAttribute{Name: "V", Type: DT_Vec4, Value: Vec{1, 2, 3, 4, 5}}, io.Discard, ErrVectorTooBig},
+		{"translated FS string arguments", NodeAttribute{Name: "T", Type: DT_TranslatedFSString, Value: TranslatedFSString{TranslatedString: TranslatedString{Handle: "h1"}, Arguments: args}},
+			&failingWriter{limit: 100}, io.ErrShortWrite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := xml.NewEncoder(tt.w)
+			err := tt.attr.MarshalXML(e, xml.StartElement{Name: xml.Name{Local: "attribute"}})
+			if err == nil {
+				err = e.Flush()
+				t.Errorf("MarshalXML returned no error, Flush returned %v", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTypeInfo(t *testing.T) {
+	tests := []struct {
+		dt   DataType
+		name string
+		id   int
+	}{
+		{DT_None, "None", 0},
+		{DT_Int, "int32", 4},
+		{DT_UUID, "guid", 31},
+		{DT_TranslatedString, "TranslatedString", 28},
+		{DT_TranslatedFSString, "TranslatedFSString", 33},
+	}
+	for _, tt := range tests {
+		name, id := NodeAttribute{Type: tt.dt}.TypeInfo()
+		if name != tt.name || id != tt.id || name != tt.dt.String() || id != tt.dt.ID() {
+			t.Errorf("TypeInfo of %v = %q, %d, want %q, %d", tt.dt, name, id, tt.name, tt.id)
+		}
+	}
+}
+
+func TestFromStringIntegerWidths(t *testing.T) {
+	tests := []struct {
+		dt      DataType
+		in      string
+		want    interface{}
+		wantErr bool
+	}{
+		{DT_Short, "-32768", int16(-32768), false},
+		{DT_Short, "32767", int16(32767), false},
+		{DT_Short, "32768", nil, true},
+		{DT_UInt, "65536", uint32(65536), false},
+		{DT_UInt, "4294967295", uint32(4294967295), false},
+		{DT_UInt, "4294967296", nil, true},
+		{DT_IVec2, "70000 -70000", Ivec{70000, -70000}, false},
+		{DT_Vec2, "0.5 -0.25", Vec{0.5, -0.25}, false},
+	}
+	for _, tt := range tests {
+		na := NodeAttribute{Name: "N", Type: tt.dt}
+		err := na.FromString(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v FromString(%q) error %v, want error: %v", tt.dt, tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(na.Value, tt.want) {
+			t.Errorf("%v FromString(%q) = %#v, want %#v", tt.dt, tt.in, na.Value, tt.want)
+		}
+	}
+}
+
+func TestFromStringTranslatedStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		na   NodeAttribute
+		want interface{}
+	}{
+		{"new translated string", NodeAttribute{Type: DT_TranslatedString}, TranslatedString{Value: "text"}},
+		{
+			"translated string with handle",
+			NodeAttribute{Type: DT_TranslatedString, Value: TranslatedString{Handle: "h1", Version: 2, Value: "old"}},
+			TranslatedString{Handle: "h1", Version: 2, Value: "text"},
+		},
+		{"new fs string", NodeAttribute{Type: DT_TranslatedFSString}, TranslatedFSString{TranslatedString: TranslatedString{Value: "text"}}},
+		{
+			"fs string with handle and arguments",
+			NodeAttribute{Type: DT_TranslatedFSString, Value: TranslatedFSString{
+				TranslatedString: TranslatedString{Handle: "h2"},
+				Arguments:        []TranslatedFSStringArgument{{Key: "k", Value: "v"}},
+			}},
+			TranslatedFSString{
+				TranslatedString: TranslatedString{Handle: "h2", Value: "text"},
+				Arguments:        []TranslatedFSStringArgument{{Key: "k", Value: "v"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.na.FromString("text"); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.na.Value, tt.want) {
+				t.Errorf("got %#v, want %#v", tt.na.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeAttributeXMLRoundTrip(t *testing.T) {
+	m := Mat(*mat.NewDense(4, 3, []float64{1, 0, 0, 0, 1, 0, 0, 0, 1, 0.5, -2, 3}))
+	tests := []NodeAttribute{
+		{Name: "Byte", Type: DT_Byte, Value: uint8(200)},
+		{Name: "Int", Type: DT_Int, Value: int32(-12)},
+		{Name: "Int64", Type: DT_Int64, Value: int64(-1) << 40},
+		{Name: "Float", Type: DT_Float, Value: float32(0.1)},
+		{Name: "Bool", Type: DT_Bool, Value: true},
+		{Name: "String", Type: DT_LSString, Value: "a <b> & \"c\""},
+		{Name: "UUID", Type: DT_UUID, Value: uuid.MustParse("f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a")},
+		{Name: "IVec", Type: DT_IVec3, Value: Ivec{1, -2, 3}},
+		{Name: "Vec", Type: DT_Vec4, Value: Vec{0.25, 0.5, 1, 2}},
+		{Name: "Mat", Type: DT_Mat4x3, Value: &m},
+		{Name: "Translated", Type: DT_TranslatedString, Value: TranslatedString{Handle: "h1", Version: 4}},
+	}
+	for _, want := range tests {
+		t.Run(want.Name, func(t *testing.T) {
+			data, err := xml.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got NodeAttribute
+			if err := xml.Unmarshal(data, &got); err != nil {
+				t.Fatalf("%s: %v", data, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("%s: got %#v, want %#v", data, got, want)
+			}
+		})
+	}
+}
+
+func TestIvecXML(t *testing.T) {
+	tests := []struct {
+		vec  Ivec
+		want string
+	}{
+		{Ivec{1, 2}, `<ivec2 x="1" y="2"></ivec2>`},
+		{Ivec{0, -5, 12}, `<ivec3 x="0" y="-5" z="12"></ivec3>`},
+		{Ivec{255, 255, 255, 0}, `<ivec4 x="255" y="255" z="255" w="0"></ivec4>`},
+	}
+	for _, tt := range tests {
+		data, err := xml.Marshal(tt.vec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("got %s, want %s", data, tt.want)
+		}
+		var got Ivec
+		if err := xml.Unmarshal([]byte(tt.want), &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.vec) {
+			t.Errorf("%s unmarshalled to %v, want %v", tt.want, got, tt.vec)
+		}
+	}
+
+	if _, err := xml.Marshal(Ivec{1, 2, 3, 4, 5}); !errors.Is(err, ErrVectorTooBig) {
+		t.Errorf("got error %v, want ErrVectorTooBig", err)
+	}
+}
+
+func TestFromStringSignedIntegers(t *testing.T) {
+	tests := []struct {
+		in       string
+		want     int64
+		unsigned bool
+	}{
+		{"+5", 5, true},
+		{"+0x10", 16, true},
+		{"-0x10", -16, false},
+		{"-5", -5, false},
+		{"0b101", 5, true},
+		{"", 0, true},
+	}
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		if !dt.IsInteger() {
+			continue
+		}
+		unsigned := dt == DT_Byte || dt == DT_UShort || dt == DT_UInt || dt == DT_ULongLong
+		for _, tt := range tests {
+			na := NodeAttribute{Name: "N", Type: dt}
+			err := na.FromString(tt.in)
+			if unsigned && !tt.unsigned {
+				if err == nil {
+					t.Errorf("%v FromString(%q) = %v, want an error", dt, tt.in, na.Value)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%v FromString(%q): %v", dt, tt.in, err)
+				continue
+			}
+			if got, _ := na.GetInt64(); got != tt.want {
+				t.Errorf("%v FromString(%q) = %v, want %d", dt, tt.in, na.Value, tt.want)
+			}
+		}
+	}
+}
+
+func TestFromStringEmptyNumbers(t *testing.T) {
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		na := NodeAttribute{Name: "N", Type: dt}
+		if !na.IsNumeric() {
+			continue
+		}
+		if err := na.FromString(""); err != nil || !na.IsZero() {
+			t.Errorf("%v FromString(\"\") = %#v, %v, want 0", dt, na.Value, err)
+		}
+	}
+}
+
+func TestUUIDRoundTrip(t *testing.T) {
+	for _, in := range []string{"f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a", "00000000-0000-0000-0000-000000000000"} {
+		na := NodeAttribute{Name: "ID", Type: DT_UUID}
+		if err := na.FromString(in); err != nil {
+			t.Fatal(err)
+		}
+		s := na.String()
+		if s != in {
+			t.Errorf("FromString(%q).String() = %q", in, s)
+		}
+		again := NodeAttribute{Name: "ID", Type: DT_UUID}
+		if err := again.FromString(s); err != nil || !again.Equal(na) {
+			t.Errorf("FromString(%q) = %v, %v, want %v", s, again.Value, err, na.Value)
+		}
+	}
+}
+
+func TestTypedAccessors(t *testing.T) {
+	id := uuid.MustParse("f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a")
+	tests := []struct {
+		na      NodeAttribute
+		int64   int64
+		uint64  uint64
+		float64 float64
+		string  string
+		uuid    uuid.UUID
+		// ok lists the accessors that succeed: i(nt64), u(int64), f(loat64), s(tring) and g(uid)
+		ok string
+	}{
+		{na: NodeAttribute{Type: DT_Byte, Value: uint8(200)}, int64: 200, uint64: 200, float64: 200, ok: "iuf"},
+		{na: NodeAttribute{Type: DT_Int64, Value: int64(-1) << 40}, int64: -1 << 40, float64: -1 << 40, ok: "if"},
+		{na: NodeAttribute{Type: DT_ULongLong, Value: uint64(math.MaxUint64)}, uint64: math.MaxUint64, float64: math.MaxUint64, ok: "uf"},
+		{na: NodeAttribute{Type: DT_Float, Value: float32(0.5)}, float64: 0.5, ok: "f"},
+		{na: NodeAttribute{Type: DT_LSString, Value: "text"}, string: "text", ok: "s"},
+		{na: NodeAttribute{Type: DT_TranslatedString, Value: TranslatedString{Handle: "h", Value: "text"}}, string: "text", ok: "s"},
+		{na: NodeAttribute{Type: DT_UUID, Value: id}, uuid: id, ok: "g"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.na.Type.String(), func(t *testing.T) {
+			check := func(accessor byte, got, want interface{}, err error) {
+				ok := strings.IndexByte(tt.ok, accessor) >= 0
+				if (err == nil) != ok || (ok && got != want) {
+					t.Errorf("accessor %c = %v, %v, want %v, success: %v", accessor, got, err, want, ok)
+				}
+			}
+			i, err := tt.na.GetInt64()
+			check('i', i, tt.int64, err)
+			u, err := tt.na.GetUint64()
+			check('u', u, tt.uint64, err)
+			f, err := tt.na.GetFloat64()
+			check('f', f, tt.float64, err)
+			s, err := tt.na.GetString()
+			check('s', s, tt.string, err)
+			g, err := tt.na.GetUUID()
+			check('g', g, tt.uuid, err)
+		})
+	}
+}
+
+func TestNodeAttributeJSON(t *testing.T) {
+	m := Mat(*mat.NewDense(2, 2, []float64{1, 2, 3, 4}))
+	tests := []struct {
+		na   NodeAttribute
+		want string
+	}{
+		{NodeAttribute{Name: "A", Type: DT_Int, Value: int32(-5)}, `{"id":"A","type":"int32","value":-5}`},
+		{NodeAttribute{Name: "A", Type: DT_ULongLong, Value: uint64(1) << 63}, `{"id":"A","type":"uint64","value":9223372036854775808}`},
+		{NodeAttribute{Name: "A", Type: DT_Float, Value: float32(0.1)}, `{"id":"A","type":"float","value":0.1}`},
+		{NodeAttribute{Name: "A", Type: DT_Bool, Value: true}, `{"id":"A","type":"bool","value":true}`},
+		{NodeAttribute{Name: "A", Type: DT_FixedString, Value: "text"}, `{"id":"A","type":"FixedString","value":"text"}`},
+		{NodeAttribute{Name: "A", Type: DT_UUID, Value: uuid.MustParse("f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a")}, `{"id":"A","type":"guid","value":"f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a"}`},
+		{NodeAttribute{Name: "A", Type: DT_IVec2, Value: Ivec{1, -2}}, `{"id":"A","type":"ivec2","value":[1,-2]}`},
+		{NodeAttribute{Name: "A", Type: DT_Vec3, Value: Vec{0.5, 1, 2}}, `{"id":"A","type":"fvec3","value":[0.5,1,2]}`},
+		{NodeAttribute{Name: "A", Type: DT_Mat2, Value: &m}, `{"id":"A","type":"mat2x2","value":[[1,2],[3,4]]}`},
+		{NodeAttribute{Name: "A", Type: DT_ScratchBuffer, Value: []byte{0, 255}}, `{"id":"A","type":"ScratchBuffer","value":"AP8="}`},
+		{NodeAttribute{Name: "A", Type: DT_TranslatedString, Value: TranslatedString{Handle: "h", Version: 1}}, `{"id":"A","type":"TranslatedString","value":{"version":1,"value":"","handle":"h"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.na.Type.String(), func(t *testing.T) {
+			data, err := json.Marshal(tt.na)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %s, want %s", data, tt.want)
+			}
+			var got NodeAttribute
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.na) {
+				t.Errorf("got %#v, want %#v", got, tt.na)
+			}
+		})
+	}
+
+	if _, err := json.Marshal(NodeAttribute{Name: "A", Type: DataType(999)}); !errors.Is(err, ErrUnknownDataType) {
+		t.Errorf("marshalling DataType(999): got error %v, want ErrUnknownDataType", err)
+	}
+}
+
+func TestNodeAttributeClone(t *testing.T) {
+	m := Mat(*mat.NewDense(3, 3, []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}))
+	tests := []struct {
+		na     NodeAttribute
+		mutate func(v interface{})
+	}{
+		{NodeAttribute{Type: DT_Vec2, Value: Vec{1, 2}}, func(v interface{}) { v.(Vec)[0] = 9 }},
+		{NodeAttribute{Type: DT_Vec3, Value: Vec{1, 2, 3}}, func(v interface{}) { v.(Vec)[2] = 9 }},
+		{NodeAttribute{Type: DT_Vec4, Value: Vec{1, 2, 3, 4}}, func(v interface{}) { v.(Vec)[3] = 9 }},
+		{NodeAttribute{Type: DT_IVec2, Value: Ivec{1, 2}}, func(v interface{}) { v.(Ivec)[0] = 9 }},
+		{NodeAttribute{Type: DT_IVec3, Value: Ivec{1, 2, 3}}, func(v interface{}) { v.(Ivec)[1] = 9 }},
+		{NodeAttribute{Type: DT_IVec4, Value: Ivec{1, 2, 3, 4}}, func(v interface{}) { v.(Ivec)[3] = 9 }},
+		{NodeAttribute{Type: DT_Mat3, Value: &m}, func(v interface{}) { (*mat.Dense)(v.(*Mat)).Set(0, 0, 9) }},
+		{NodeAttribute{Type: DT_ScratchBuffer, Value: []byte{1, 2}}, func(v interface{}) { v.([]byte)[0] = 9 }},
+		{
+			NodeAttribute{Type: DT_TranslatedFSString, Value: TranslatedFSString{
+				TranslatedString: TranslatedString{Handle: "h1"},
+				Arguments: []TranslatedFSStringArgument{{Key: "Name", String: TranslatedFSString{
+					Arguments: []TranslatedFSStringArgument{{Key: "Inner"}},
+				}}},
+			}},
+			func(v interface{}) { v.(TranslatedFSString).Arguments[0].String.Arguments[0].Key = "changed" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.na.Type.String(), func(t *testing.T) {
+			tt.na.Name = "A"
+			tt.na.ExtraAttrs = []xml.Attr{{Name: xml.Name{Local: "note"}, Value: "x"}}
+			original := tt.na.Clone()
+			clone := tt.na.Clone()
+			tt.mutate(clone.Value)
+			clone.ExtraAttrs[0].Value = "changed"
+			if !tt.na.Equal(original) || tt.na.ExtraAttrs[0].Value != "x" {
+				t.Errorf("changing the clone changed the original: %v", tt.na)
+			}
+			if tt.na.Equal(clone) {
+				t.Errorf("changing the clone did not change it: %v", clone)
+			}
+		})
+	}
+}
+
+func TestUnknownDataType(t *testing.T) {
+	dt := DataType(999)
+	if got := dt.String(); got != "Unknown(999)" {
+		t.Errorf("got %q, want Unknown(999)", got)
+	}
+	tests := []struct {
+		name    string
+		marshal func(v interface{}) ([]byte, error)
+	}{
+		{"xml", xml.Marshal},
+		{"json", json.Marshal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			na := NodeAttribute{Name: "A", Type: dt, Value: int32(1)}
+			if data, err := tt.marshal(na); !errors.Is(err, ErrUnknownDataType) {
+				t.Errorf("got %s, error %v, want ErrUnknownDataType", data, err)
+			}
+		})
+	}
+}
+
+func TestVecMatElementXML(t *testing.T) {
+	m := Mat(*mat.NewDense(4, 3, []float64{1, 0, 0, 0, 1, 0, 0, 0, 1, 0.5, -2, 3}))
+	var (
+		vec Vec
+		got Mat
+	)
+	tests := []struct {
+		name  string
+		value interface{}
+		into  interface{}
+		want  string
+	}{
+		{"Vec4", Vec{0.25, 0.5, 1, 2}, &vec, `<float4 x="0.25" y="0.5" z="1" w="2"></float4>`},
+		{"Mat4x3", m, &got, `<mat4x3><float3 x="1" y="0" z="0"></float3>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := xml.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(data), tt.want) {
+				t.Errorf("got %s, want prefix %s", data, tt.want)
+			}
+			if err := xml.Unmarshal(data, tt.into); err != nil {
+				t.Fatalf("%s: %v", data, err)
+			}
+		})
+	}
+	if !reflect.DeepEqual(vec, Vec{0.25, 0.5, 1, 2}) {
+		t.Errorf("got vector %v", vec)
+	}
+	if !mat.Equal((*mat.Dense)(&got), (*mat.Dense)(&m)) {
+		t.Errorf("got matrix %v", mat.Formatted((*mat.Dense)(&got)))
+	}
+}
+
+func TestVecMatElementXMLDimensions(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		into interface{}
+	}{
+		{"vector missing a component", `<float4 x="1" y="2" z="3"/>`, &Vec{}},
+		{"vector with an extra component", `<float2 x="1" y="2" z="3"/>`, &Vec{}},
+		{"matrix missing a row", `<mat3><float3 x="1" y="0" z="0"/><float3 x="0" y="1" z="0"/></mat3>`, &Mat{}},
+		{"matrix with short rows", `<mat2x3><float2 x="1" y="0"/><float2 x="0" y="1"/></mat2x3>`, &Mat{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := xml.Unmarshal([]byte(tt.data), tt.into); err == nil {
+				t.Errorf("unmarshaling %s did not fail", tt.data)
+			}
+		})
+	}
+}
+
+func TestDataTypeCategories(t *testing.T) {
+	categories := map[DataType]string{
+		DT_None:               "",
+		DT_Byte:               "integer",
+		DT_Short:              "integer",
+		DT_UShort:             "integer",
+		DT_Int:                "integer",
+		DT_UInt:               "integer",
+		DT_Float:              "float",
+		DT_Double:             "float",
+		DT_IVec2:              "vector",
+		DT_IVec3:              "vector",
+		DT_IVec4:              "vector",
+		DT_Vec2:               "vector",
+		DT_Vec3:               "vector",
+		DT_Vec4:               "vector",
+		DT_Mat2:               "matrix",
+		DT_Mat3:               "matrix",
+		DT_Mat3x4:             "matrix",
+		DT_Mat4x3:             "matrix",
+		DT_Mat4:               "matrix",
+		DT_Bool:               "",
+		DT_String:             "string",
+		DT_Path:               "string",
+		DT_FixedString:        "string",
+		DT_LSString:           "string",
+		DT_ULongLong:          "integer",
+		DT_ScratchBuffer:      "",
+		DT_Long:               "integer",
+		DT_Int8:               "integer",
+		DT_TranslatedString:   "string",
+		DT_WString:            "string",
+		DT_LSWString:          "string",
+		DT_UUID:               "",
+		DT_Int64:              "integer",
+		DT_TranslatedFSString: "string",
+	}
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		t.Run(dt.String(), func(t *testing.T) {
+			want, ok := categories[dt]
+			if !ok {
+				t.Fatal("no category in the test table")
+			}
+			got := map[string]bool{
+				"integer": dt.IsInteger(),
+				"float":   dt.IsFloatingPoint(),
+				"vector":  dt.IsVector(),
+				"matrix":  dt.IsMatrix(),
+				"string":  dt.IsString(),
+			}
+			for category, is := range got {
+				if is != (category == want) {
+					t.Errorf("%s: got %v, want %v", category, is, category == want)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeAttributeEqual(t *testing.T) {
+	m1 := Mat(*mat.NewDense(2, 2, []float64{1, 2, 3, 4}))
+	m2 := Mat(*mat.NewDense(2, 2, []float64{1, 2, 3, 4}))
+	m3 := Mat(*mat.NewDense(2, 2, []float64{1, 2, 3, 5}))
+	id := uuid.MustParse("f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a")
+	tests := []struct {
+		name string
+		dt   DataType
+		a, b interface{}
+		want bool
+	}{
+		{"integer equal", DT_Int, int32(1), int32(1), true},
+		{"integer unequal", DT_Int, int32(1), int32(2), false},
+		{"integer width", DT_Int, int32(1), int64(1), false},
+		{"float equal", DT_Float, float32(0.5), float32(0.5), true},
+		{"float unequal", DT_Float, float32(0.5), float32(0.25), false},
+		{"string equal", DT_LSString, "a", "a", true},
+		{"string unequal", DT_LSString, "a", "b", false},
+		{"uuid equal", DT_UUID, id, id, true},
+		{"uuid unequal", DT_UUID, id, uuid.Nil, false},
+		{"vector equal", DT_Vec3, Vec{1, 2, 3}, Vec{1, 2, 3}, true},
+		{"vector unequal", DT_Vec3, Vec{1, 2, 3}, Vec{1, 2, 4}, false},
+		{"integer vector equal", DT_IVec2, Ivec{1, 2}, Ivec{1, 2}, true},
+		{"integer vector unequal", DT_IVec2, Ivec{1, 2}, Ivec{2, 1}, false},
+		{"matrix equal", DT_Mat2, &m1, &m2, true},
+		{"matrix unequal", DT_Mat2, &m1, &m3, false},
+		{"matrix nil", DT_Mat2, &m1, (*Mat)(nil), false},
+		{"bytes equal", DT_ScratchBuffer, []byte{1, 2}, []byte{1, 2}, true},
+		{"bytes unequal", DT_ScratchBuffer, []byte{1, 2}, []byte{1}, false},
+		{"translated string equal", DT_TranslatedString, TranslatedString{Handle: "h", Version: 1}, TranslatedString{Handle: "h", Version: 1}, true},
+		{"translated string unequal", DT_TranslatedString, TranslatedString{Handle: "h", Version: 1}, TranslatedString{Handle: "h", Version: 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NodeAttribute{Name: "A", Type: tt.dt, Value: tt.a}
+			b := NodeAttribute{Name: "A", Type: tt.dt, Value: tt.b}
+			if got := a.Equal(b); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if got := b.Equal(a); got != tt.want {
+				t.Errorf("reversed: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	a := NodeAttribute{Name: "A", Type: DT_Int, Value: int32(1)}
+	if a.Equal(NodeAttribute{Name: "B", Type: DT_Int, Value: int32(1)}) || a.Equal(NodeAttribute{Name: "A", Type: DT_UInt, Value: int32(1)}) {
+		t.Error("attributes with different names or types are equal")
+	}
+}
+
+func TestCanonicalString(t *testing.T) {
+	tests := []struct {
+		dt     DataType
+		inputs []string
+		want   string
+	}{
+		{DT_Float, []string{"1", "1.0", "1.000", "1e0"}, "1"},
+		{DT_Float, []string{"0", "-0", "0.0"}, "0"},
+		{DT_Double, []string{"0.25", "2.5e-1", ".25"}, "0.25"},
+		{DT_Bool, []string{"True", "true", "1"}, "True"},
+		{DT_Bool, []string{"False", "false", "0"}, "False"},
+		{DT_UUID, []string{"F5A0BC1B-7B9A-4A0C-8A0E-6D6F1F0B5F3A", "f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a"}, "f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a"},
+		{DT_Vec3, []string{"1 2 3", "1.0 2.00 3e0", "1 2.0 3"}, "1 2 3"},
+		{DT_Mat2, []string{"1 0 0 1", "1.0 -0 0.0 1"}, "1 0 0 1"},
+		{DT_Int, []string{"42", "+42"}, "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dt.String()+" "+tt.want, func(t *testing.T) {
+			for _, in := range tt.inputs {
+				na := NodeAttribute{Name: "A", Type: tt.dt}
+				if err := na.FromString(in); err != nil {
+					t.Fatalf("%q: %v", in, err)
+				}
+				if got := na.CanonicalString(); got != tt.want {
+					t.Errorf("%q: got %q, want %q", in, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAttributeTypeByID(t *testing.T) {
+	// Type IDs of LSLib (LSLib/LS/NodeAttribute.cs)
+	known := []struct {
+		id uint8
+		dt DataType
+	}{
+		{0, DT_None},
+		{1, DT_Byte},
+		{7, DT_Double},
+		{19, DT_Bool},
+		{24, DT_ULongLong},
+		{26, DT_Long},
+		{27, DT_Int8},
+		{31, DT_UUID},
+		{33, DT_TranslatedFSString},
+	}
+	for _, tt := range known {
+		if got, err := AttributeTypeByID(tt.id); err != nil || got != tt.dt {
+			t.Errorf("ID %d: got %v, %v, want %v", tt.id, got, err, tt.dt)
+		}
+	}
+
+	for id := 0; id <= math.MaxUint8; id++ {
+		dt, err := AttributeTypeByID(uint8(id))
+		if id > int(DT_Max) {
+			if !errors.Is(err, ErrUnknownDataType) {
+				t.Errorf("ID %d: got %v, %v, want ErrUnknownDataType", id, dt, err)
+			}
+			if _, err := DataType(id).BinaryID(); !errors.Is(err, ErrUnknownDataType) {
+				t.Errorf("BinaryID of %d: got error %v, want ErrUnknownDataType", id, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ID %d: %v", id, err)
+			continue
+		}
+		if back, err := dt.BinaryID(); err != nil || int(back) != id {
+			t.Errorf("ID %d: %v has binary ID %d, %v", id, dt, back, err)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	fromString := func(dt DataType, s string) func() error {
+		return func() error {
+			na := NodeAttribute{Name: "A", Type: dt}
+			return na.FromString(s)
+		}
+	}
+	badVersion := lsfFile(t, namedResource(1), VerBG3)
+	badVersion[4] = byte(MaxVersion + 1)
+	tests := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{"integer", fromString(DT_Int, "one"), ErrNotNumeric},
+		{"float", fromString(DT_Float, "1,5"), ErrNotNumeric},
+		{"vector component", fromString(DT_Vec2, "1 x"), ErrNotNumeric},
+		{"vector length", fromString(DT_IVec3, "1 2"), ErrDimensionMismatch},
+		{"matrix size", fromString(DT_Mat2, "1 2 3"), ErrDimensionMismatch},
+		{"unknown type", fromString(DataType(99), "1"), ErrUnknownDataType},
+		{"rows", func() error { _, err := DT_Int.GetRows(); return err }, ErrDimensionMismatch},
+		{"columns", func() error { _, err := DT_LSString.GetColumns(); return err }, ErrDimensionMismatch},
+		{"parse type", func() error { _, err := ParseDataType("float5"); return err }, ErrUnknownDataType},
+		{"read version", func() error { _, err := ReadLSF(bytes.NewReader(badVersion)); return err }, ErrUnsupportedVersion},
+		{"write version", func() error { return (LSFWriter{Version: MaxVersion + 1}).Write(&bytes.Buffer{}, namedResource(1)) }, ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	m := Mat(*mat.NewDense(3, 3, []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}))
+	tests := []struct {
+		name    string
+		dt      DataType
+		value   interface{}
+		want    interface{}
+		wantErr error
+	}{
+		{"int", DT_Int, int32(5), int32(5), nil},
+		{"int from an untyped constant", DT_Int, 5, int32(5), nil},
+		{"byte from an int", DT_Byte, 200, uint8(200), nil},
+		{"float", DT_Float, 0.5, float32(0.5), nil},
+		{"string", DT_LSString, "text", "text", nil},
+		{"vector", DT_Vec3, Vec{1, 2, 3}, Vec{1, 2, 3}, nil},
+		{"integer vector", DT_IVec2, Ivec{1, 2}, Ivec{1, 2}, nil},
+		{"matrix", DT_Mat3, &m, &m, nil},
+		{"short vector", DT_Vec4, Vec{1, 2, 3}, nil, ErrDimensionMismatch},
+		{"long integer vector", DT_IVec2, Ivec{1, 2, 3}, nil, ErrDimensionMismatch},
+		{"wrong matrix", DT_Mat4, &m, nil, ErrDimensionMismatch},
+		{"empty matrix", DT_Mat3, &Mat{}, nil, ErrDimensionMismatch},
+		{"out of range", DT_Byte, 256, nil, strconv.ErrRange},
+		{"unknown type", DataType(99), 1, nil, ErrUnknownDataType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			na := NodeAttribute{Name: "A", Type: tt.dt}
+			err := na.SetValue(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !na.Equal(NodeAttribute{Name: "A", Type: tt.dt, Value: tt.want}) {
+				t.Errorf("got %#v, want %#v", na.Value, tt.want)
+			}
+			if tt.wantErr != nil && na.Value != nil {
+				t.Errorf("a rejected value was stored: %#v", na.Value)
+			}
+		})
+	}
+
+	for _, v := range []interface{}{"5", nil, Vec{1}, true} {
+		na := NodeAttribute{Name: "A", Type: DT_Int}
+		if err := na.SetValue(v); err == nil {
+			t.Errorf("%#v was stored as int32", v)
+		}
+	}
+}
+
+func TestMatrixRowMajor(t *testing.T) {
+	tests := []struct {
+		dt         DataType
+		in         string
+		rows, cols int
+	}{
+		{DT_Mat2, "1 2 3 4", 2, 2},
+		{DT_Mat3, "1 2 3 4 5 6 7 8 9", 3, 3},
+		{DT_Mat3x4, "1 2 3 4 5 6 7 8 9 10 11 12", 3, 4},
+		{DT_Mat4x3, "1 2 3 4 5 6 7 8 9 10 11 12", 4, 3},
+		{DT_Mat4, "1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16", 4, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dt.String(), func(t *testing.T) {
+			na := NodeAttribute{Name: "M", Type: tt.dt}
+			if err := na.FromString(tt.in); err != nil {
+				t.Fatal(err)
+			}
+			m := (*mat.Dense)(na.Value.(*Mat))
+			if r, c := m.Dims(); r != tt.rows || c != tt.cols {
+				t.Fatalf("got %dx%d, want %dx%d", r, c, tt.rows, tt.cols)
+			}
+			// Every cell holds its row-major position, a transposed matrix would not
+			for i := 0; i < tt.rows; i++ {
+				for j := 0; j < tt.cols; j++ {
+					if got, want := m.At(i, j), float64(i*tt.cols+j+1); got != want {
+						t.Errorf("cell %d,%d: got %v, want %v", i, j, got, want)
+					}
+				}
+			}
+			if got := na.CanonicalString(); got != tt.in {
+				t.Errorf("got %q, want %q", got, tt.in)
+			}
+
+			data, err := xml.Marshal(na)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got NodeAttribute
+			if err := xml.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(na) {
+				t.Errorf("%s did not round-trip", data)
+			}
+
+			data, err = xml.Marshal(*na.Value.(*Mat))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var element Mat
+			if err := xml.Unmarshal(data, &element); err != nil {
+				t.Fatal(err)
+			}
+			if !mat.Equal((*mat.Dense)(&element), m) {
+				t.Errorf("%s did not round-trip", data)
+			}
+		})
+	}
+}
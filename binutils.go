@@ -83,48 +83,111 @@ func MakeCompressionFlags(method CompressionMethod, level CompressionLevel) int
 	return flags | int(level)
 }
 
-func Decompress(compressed io.Reader, uncompressedSize int, compressionFlags byte, chunked bool) io.ReadSeeker {
-	switch CompressionMethod(compressionFlags & 0x0f) {
+// Decompress decompresses data using method. uncompressedSize is the size of the decompressed data stored
+// alongside it, LZ4 blocks do not record their own size. Chunked data is an LZ4 frame instead of a single block.
+func Decompress(data []byte, uncompressedSize int, method CompressionMethod, chunked bool) ([]byte, error) {
+	switch method {
 	case CMNone:
-		// logger.Println("No compression")
-		if v, ok := compressed.(io.ReadSeeker); ok {
-			return v
-		}
-		panic(errors.New("compressed must be an io.ReadSeeker if there is no compression"))
+		return data, nil
 
 	case CMZlib:
-		// logger.Println("zlib compression")
-		zr, _ := zlib.NewReader(compressed)
-		v, _ := ioutil.ReadAll(zr)
-		return bytes.NewReader(v)
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		p := make([]byte, uncompressedSize)
+		_, err = io.ReadFull(zr, p)
+		if err != nil {
+			return nil, err
+		}
+		// Read to the end of the stream so that the checksum is verified
+		_, err = io.Copy(ioutil.Discard, zr)
+		if err != nil {
+			return nil, err
+		}
+		return p, zr.Close()
 
 	case CMLZ4:
 		if chunked {
-			// logger.Println("lz4 stream compressed")
-			zr := lz4.NewReader(compressed)
 			p := make([]byte, uncompressedSize)
-			_, err := zr.Read(p)
-			if err != nil {
-				panic(err)
-			}
-			return bytes.NewReader(p)
-		} else {
-			// logger.Println("lz4 block compressed")
-			// panic(errors.New("not implemented"))
-			src, _ := ioutil.ReadAll(compressed)
-			// logger.Println(len(src))
-			dst := make([]byte, uncompressedSize*2)
-			_, err := lz4.UncompressBlock(src, dst)
-			if err != nil {
-				panic(err)
-			}
+			zr := lz4.NewReader(bytes.NewReader(data))
+			_, err := io.ReadFull(zr, p)
+			return p, err
+		}
+		return decompressLZ4(data, uncompressedSize)
+
+	default:
+		return nil, fmt.Errorf("No decompressor found for this format: %v", method)
+	}
+}
+
+// Compress compresses data using method and level. Zlib levels map to zlib.BestSpeed, zlib.DefaultCompression
+// and zlib.BestCompression, LZ4 data is a single block without a frame header which is compressed
+// with the high compression compressor for MaxCompression.
+func Compress(data []byte, method CompressionMethod, level CompressionLevel) ([]byte, error) {
+	switch method {
+	case CMNone:
+		return data, nil
 
-			return bytes.NewReader(dst)
+	case CMZlib:
+		var (
+			buf       bytes.Buffer
+			zlibLevel = zlib.DefaultCompression
+		)
+		switch level {
+		case FastCompression:
+			zlibLevel = zlib.BestSpeed
+		case MaxCompression:
+			zlibLevel = zlib.BestCompression
+		}
+		zw, err := zlib.NewWriterLevel(&buf, zlibLevel)
+		if err != nil {
+			return nil, err
+		}
+		_, err = zw.Write(data)
+		if err != nil {
+			return nil, err
 		}
+		err = zw.Close()
+		return buf.Bytes(), err
+
+	case CMLZ4:
+		return compressLZ4(data, level)
 
 	default:
-		panic(fmt.Errorf("No decompressor found for this format: %v", compressionFlags))
+		return nil, fmt.Errorf("No compressor found for this format: %v", method)
+	}
+}
+
+// compressLZ4 compresses src as a single LZ4 block
+func compressLZ4(src []byte, level CompressionLevel) ([]byte, error) {
+	var (
+		dst = make([]byte, lz4.CompressBlockBound(len(src)))
+		n   int
+		err error
+	)
+	if level == MaxCompression {
+		n, err = lz4.CompressBlockHC(src, dst, lz4.Level9, nil, nil)
+	} else {
+		n, err = lz4.CompressBlock(src, dst, nil)
 	}
+	return dst[:n], err
+}
+
+// decompressLZ4 decompresses the LZ4 block src, which must decompress to exactly decompressedSize bytes
+func decompressLZ4(src []byte, decompressedSize int) ([]byte, error) {
+	dst := make([]byte, decompressedSize)
+	if len(src) == 0 && decompressedSize == 0 {
+		return dst, nil
+	}
+	n, err := lz4.UncompressBlock(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	if n != decompressedSize {
+		return nil, fmt.Errorf("LZ4 block decompressed to %d bytes, expected %d", n, decompressedSize)
+	}
+	return dst, nil
 }
 
 func ReadCString(r io.Reader, length int) (string, error) {
@@ -372,7 +435,10 @@ func ReadAttribute(r io.ReadSeeker, name string, DT DataType, length uint, l log
 	case DT_UUID:
 		var v uuid.UUID
 		p := make([]byte, 16)
-		n, err = r.Read(p)
+		n, err = io.ReadFull(r, p)
+		if err != nil {
+			return attr, err
+		}
 		reverse(p[:4])
 		reverse(p[4:6])
 		reverse(p[6:8])
@@ -394,6 +460,93 @@ func ReadAttribute(r io.ReadSeeker, name string, DT DataType, length uint, l log
 	return attr, nil
 }
 
+// WriteBinary writes the value of na in little-endian byte order. Scalars, vectors, matrices and UUIDs are
+// written as they are stored in LSF files, strings and scratch buffers are preceded by their length as an
+// int32 so that ReadBinary does not need to know it, and translated strings use the BG3 layout.
+func (na NodeAttribute) WriteBinary(w io.Writer) error {
+	switch na.Type {
+	case DT_String, DT_Path, DT_FixedString, DT_LSString, DT_WString, DT_LSWString:
+		v, ok := na.Value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string for %v, got %T", na.Type, na.Value)
+		}
+		return writeLengthString(w, v)
+
+	case DT_ScratchBuffer:
+		v, ok := na.Value.([]byte)
+		if !ok {
+			return fmt.Errorf("expected a []byte for %v, got %T", na.Type, na.Value)
+		}
+		err := binary.Write(w, binary.LittleEndian, int32(len(v)))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(v)
+		return err
+
+	default:
+		return writeLSFAttribute(w, na, MaxVersion, 0)
+	}
+}
+
+// ReadBinary sets the value of na by reading a value of type na.Type written by WriteBinary
+func (na *NodeAttribute) ReadBinary(r io.ReadSeeker) error {
+	switch na.Type {
+	case DT_String, DT_Path, DT_FixedString, DT_LSString, DT_WString, DT_LSWString:
+		var length int32
+		err := binary.Read(r, binary.LittleEndian, &length)
+		if err != nil {
+			return err
+		}
+		if length < 1 {
+			return fmt.Errorf("invalid string length %d", length)
+		}
+		v, err := ReadCString(r, int(length))
+		if err != nil {
+			return err
+		}
+		na.Value = v
+
+	case DT_ScratchBuffer:
+		var length int32
+		err := binary.Read(r, binary.LittleEndian, &length)
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			return fmt.Errorf("invalid scratch buffer length %d", length)
+		}
+		v := make([]byte, length)
+		_, err = io.ReadFull(r, v)
+		if err != nil {
+			return err
+		}
+		na.Value = v
+
+	case DT_TranslatedString:
+		v, err := ReadTranslatedString(r, MaxVersion, 0)
+		if err != nil {
+			return err
+		}
+		na.Value = v
+
+	case DT_TranslatedFSString:
+		v, err := ReadTranslatedFSString(r, MaxVersion)
+		if err != nil {
+			return err
+		}
+		na.Value = v
+
+	default:
+		attr, err := ReadAttribute(r, na.Name, na.Type, 0, log.NewNopLogger())
+		if err != nil {
+			return err
+		}
+		na.Value = attr.Value
+	}
+	return nil
+}
+
 // LimitReader returns a Reader that reads from r
 // but stops with EOF after n bytes.
 // The underlying implementation is a *LimitedReader.
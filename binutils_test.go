@@ -0,0 +1,170 @@
+package lslib
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		size    int
+		method  CompressionMethod
+		chunked bool
+		want    string
+		wantErr bool
+	}{
+		{"none", []byte("hello"), 5, CMNone, false, "hello", false},
+		{"none empty", []byte{}, 0, CMNone, false, "", false},
+		{"zlib", []byte{0x78, 0x9c, 0xcb, 0x48, 0xcd, 0xc9, 0xc9, 0x07, 0x00, 0x06, 0x2c, 0x02, 0x15}, 5, CMZlib, false, "hello", false},
+		{"zlib empty", []byte{0x78, 0x9c, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01}, 0, CMZlib, false, "", false},
+		{"zlib bad checksum", []byte{0x78, 0x9c, 0xcb, 0x48, 0xcd, 0xc9, 0xc9, 0x07, 0x00, 0x06, 0x2c, 0x02, 0x16}, 5, CMZlib, false, "", true},
+		{"zlib truncated", []byte{0x78, 0x9c, 0xcb, 0x48}, 5, CMZlib, false, "", true},
+		{"lz4 block", []byte{0x50, 'h', 'e', 'l', 'l', 'o'}, 5, CMLZ4, false, "hello", false},
+		{"lz4 block with match", []byte{0x14, 'a', 0x01, 0x00, 0x50, 'b', 'b', 'b', 'b', 'b'}, 14, CMLZ4, false, "aaaaaaaaabbbbb", false},
+		{"lz4 empty block", []byte{0x00}, 0, CMLZ4, false, "", false},
+		{"lz4 wrong size", []byte{0x50, 'h', 'e', 'l', 'l', 'o'}, 8, CMLZ4, false, "", true},
+		{"invalid method", []byte("hello"), 5, CMInvalid, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decompress(tt.data, tt.size, tt.method, tt.chunked)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("LSF section data "), 100)
+	for _, method := range []CompressionMethod{CMNone, CMZlib, CMLZ4} {
+		for _, level := range []CompressionLevel{FastCompression, DefaultCompression, MaxCompression} {
+			for _, chunked := range []bool{false, true} {
+				compressed, err := compressSection(data, method, level, chunked)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := Decompress(compressed, len(data), method, chunked)
+				if err != nil || !bytes.Equal(got, data) {
+					t.Errorf("method %d level %#x chunked %v: got %d bytes, %v", method, level, chunked, len(got), err)
+				}
+			}
+		}
+	}
+}
+
+func TestCompressPayloadSizes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, 1, 15, 4096, 65536, 200000} {
+		payloads := map[string][]byte{
+			"random":     make([]byte, size),
+			"repetitive": bytes.Repeat([]byte{'a'}, size),
+		}
+		rng.Read(payloads["random"])
+		for kind, data := range payloads {
+			for _, method := range []CompressionMethod{CMNone, CMZlib, CMLZ4} {
+				for _, level := range []CompressionLevel{FastCompression, DefaultCompression, MaxCompression} {
+					compressed, err := Compress(data, method, level)
+					if err != nil {
+						t.Fatalf("%s %d bytes method %d level %#x: %v", kind, size, method, level, err)
+					}
+					got, err := Decompress(compressed, len(data), method, false)
+					if err != nil || !bytes.Equal(got, data) {
+						t.Errorf("%s %d bytes method %d level %#x: got %d bytes, %v", kind, size, method, level, len(got), err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLZ4Block(t *testing.T) {
+	payloads := map[string][]byte{
+		"empty":      {},
+		"short":      []byte("hello"),
+		"node names": []byte("TemplateIdMapKeyTemplateIdMapKeyTemplateIdMapKeyTemplateIdMapKey"),
+		"zeros":      make([]byte, 100000),
+	}
+	for name, data := range payloads {
+		for _, level := range []CompressionLevel{DefaultCompression, MaxCompression} {
+			compressed, err := compressLZ4(data, level)
+			if err != nil {
+				t.Fatalf("%s level %#x: %v", name, level, err)
+			}
+			if len(data) > 1000 && len(compressed) > len(data)/100 {
+				t.Errorf("%s level %#x: compressed to %d bytes", name, level, len(compressed))
+			}
+			got, err := decompressLZ4(compressed, len(data))
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("%s level %#x: got %d bytes, %v", name, level, len(got), err)
+			}
+		}
+	}
+
+	// A block holding the literals "hello", as written by the reference implementation
+	got, err := decompressLZ4([]byte{0x50, 'h', 'e', 'l', 'l', 'o'}, 5)
+	if err != nil || string(got) != "hello" {
+		t.Errorf("got %q, %v, want hello", got, err)
+	}
+	if _, err := decompressLZ4([]byte{0x50, 'h', 'e', 'l', 'l', 'o'}, 4); err == nil {
+		t.Error("a block larger than the expected size was accepted")
+	}
+}
+
+func TestNodeAttributeBinary(t *testing.T) {
+	tests := []struct {
+		dt    DataType
+		value interface{}
+		want  []byte
+	}{
+		{DT_Byte, uint8(0xfe), []byte{0xfe}},
+		{DT_Int8, int8(-2), []byte{0xfe}},
+		{DT_Short, int16(-2), []byte{0xfe, 0xff}},
+		{DT_UShort, uint16(0x1234), []byte{0x34, 0x12}},
+		{DT_Int, int32(-2), []byte{0xfe, 0xff, 0xff, 0xff}},
+		{DT_UInt, uint32(0x12345678), []byte{0x78, 0x56, 0x34, 0x12}},
+		{DT_Long, int64(-2), []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{DT_Int64, int64(0x0102030405060708), []byte{8, 7, 6, 5, 4, 3, 2, 1}},
+		{DT_ULongLong, uint64(1) << 63, []byte{0, 0, 0, 0, 0, 0, 0, 0x80}},
+		{DT_Float, float32(0.5), []byte{0, 0, 0, 0x3f}},
+		{DT_Double, 0.5, []byte{0, 0, 0, 0, 0, 0, 0xe0, 0x3f}},
+		{DT_Bool, true, []byte{1}},
+		{DT_Vec3, Vec{1, 2, 3}, []byte{0, 0, 0x80, 0x3f, 0, 0, 0, 0x40, 0, 0, 0x40, 0x40}},
+		{DT_UUID, uuid.MustParse("00112233-4455-6677-8899-aabbccddeeff"), []byte{
+			0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff,
+		}},
+		{DT_LSString, "ab", []byte{3, 0, 0, 0, 'a', 'b', 0}},
+		{DT_ScratchBuffer, []byte{9, 8}, []byte{2, 0, 0, 0, 9, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dt.String(), func(t *testing.T) {
+			na := NodeAttribute{Name: "A", Type: tt.dt, Value: tt.value}
+			var buf bytes.Buffer
+			if err := na.WriteBinary(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("got % x, want % x", buf.Bytes(), tt.want)
+			}
+			got := NodeAttribute{Name: "A", Type: tt.dt}
+			if err := got.ReadBinary(bytes.NewReader(buf.Bytes())); err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(na) {
+				t.Errorf("got %#v, want %#v", got.Value, tt.value)
+			}
+		})
+	}
+
+	if err := (NodeAttribute{Name: "A", Type: DT_Int, Value: "1"}).WriteBinary(&bytes.Buffer{}); err == nil {
+		t.Error("writing a string as DT_Int did not fail")
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/xml"
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
@@ -74,8 +74,8 @@ func openLSF(filename string) error {
 	var (
 		l   *lslib.Resource
 		err error
-		n   string
-		f   strwr
+		n   bytes.Buffer
+		f   io.Writer
 	)
 	l, err = readLSF(filename)
 	if err != nil {
@@ -85,7 +85,7 @@ func openLSF(filename string) error {
 		pretty.Log(l)
 	}
 	if *printXML || *write {
-		n, err = marshalXML(l)
+		err = lslib.LSXWriter{}.Write(&n, l)
 		if err != nil {
 			return fmt.Errorf("Creating XML from LSF file %s failed: %w\n", filename, err)
 		}
@@ -99,8 +99,7 @@ func openLSF(filename string) error {
 			f = os.Stdout
 		}
 
-		err = writeXML(f, n)
-		fmt.Fprint(f, "\n")
+		_, err = n.WriteTo(f)
 		if err != nil {
 			return fmt.Errorf("Writing XML from LSF file %s failed: %w\n", filename, err)
 		}
@@ -110,61 +109,14 @@ func openLSF(filename string) error {
 
 func readLSF(filename string) (*lslib.Resource, error) {
 	var (
-		l   lslib.Resource
 		f   *os.File
 		err error
 	)
 	f, err = os.Open(filename)
-	defer f.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	l, err = lslib.ReadLSF(f)
 	if err != nil {
 		return nil, err
 	}
-	return &l, nil
-}
-
-func marshalXML(l *lslib.Resource) (string, error) {
-	var (
-		v   []byte
-		err error
-	)
-	v, err = xml.MarshalIndent(struct {
-		*lslib.Resource
-		XMLName string `xml:"save"`
-	}{l, ""}, "", "\t")
-	if err != nil {
-		return string(v), err
-	}
-	n := string(v)
-	n = strings.ReplaceAll(n, "></version>", " />")
-	n = strings.ReplaceAll(n, "></attribute>", " />")
-	n = strings.ReplaceAll(n, "></node>", " />")
-	n = strings.ReplaceAll(n, "false", "False")
-	n = strings.ReplaceAll(n, "true", "True")
-	n = strings.ReplaceAll(n, "&#39;", "'")
-	return n, nil
-}
-
-type strwr interface {
-	io.Writer
-	io.StringWriter
-}
+	defer f.Close()
 
-func writeXML(f strwr, n string) error {
-	var (
-		err error
-	)
-	_, err = f.WriteString(strings.ToLower(xml.Header))
-	if err != nil {
-		return err
-	}
-	_, err = f.WriteString(n)
-	if err != nil {
-		return err
-	}
-	return nil
+	return lslib.ReadLSF(f)
 }
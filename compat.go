@@ -0,0 +1,192 @@
+package lslib
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// CanConvertLossless reports whether res can be written in the target format without losing data, version is the
+// LSF file version and is ignored for other formats. If the conversion is lossy the reasons are returned, each
+// prefixed with the slash separated path of the node it applies to.
+//
+// No DataType is tied to an LSF version: every type has the same binary ID and is written by every version, so no
+// attribute is lossy only because of its type. What newer versions add is the version of translated strings, which
+// older versions do not store and which is reported when it is set.
+func CanConvertLossless(res *Resource, target Format, version FileVersion) (bool, []string) {
+	var reasons []string
+	switch target {
+	case FormatLSF:
+		if _, err := ParseFileVersion(uint32(version)); err != nil {
+			return false, []string{err.Error()}
+		}
+	case FormatLSX, FormatLSJ:
+	default:
+		return false, []string{fmt.Sprintf("%v files can not be written", target)}
+	}
+	if res.Metadata.Timestamp != 0 {
+		reasons = append(reasons, fmt.Sprintf("the timestamp is not stored in %v files", target))
+	}
+
+	c := conversion{
+		target:        target,
+		version:       version,
+		engineVersion: res.Metadata.MajorVersion<<28 | res.Metadata.MinorVersion<<24 | res.Metadata.Revision<<16 | res.Metadata.BuildNumber,
+	}
+	var check func(n *Node, path string)
+	check = func(n *Node, path string) {
+		path += "/" + n.Name
+		if target == FormatLSJ {
+			reasons = append(reasons, lsjNodeLoss(n, path[1:])...)
+		}
+		for _, attr := range n.Attributes {
+			for _, reason := range c.attributeLoss(attr) {
+				reasons = append(reasons, fmt.Sprintf("%s: attribute %s: %s", path[1:], attr.Name, reason))
+			}
+		}
+		for _, child := range n.Children {
+			check(child, path)
+		}
+	}
+	for _, region := range res.Regions {
+		check(region, "")
+	}
+	return len(reasons) == 0, reasons
+}
+
+// conversion is the format, LSF version and engine version a resource is written with
+type conversion struct {
+	target        Format
+	version       FileVersion
+	engineVersion uint32
+}
+
+// lsjNodeLoss returns the parts of the structure of n that LSJ can not represent: LSJ objects are keyed by name and
+// hold the children of a node grouped by name next to its attributes, and the root node of a region is stored under
+// the region name
+func lsjNodeLoss(n *Node, path string) []string {
+	var reasons []string
+	if n.Parent == nil && n.RegionName != "" && n.Name != n.RegionName {
+		reasons = append(reasons, fmt.Sprintf("%s: the node name differs from the region name %s", path, n.RegionName))
+	}
+	names := map[string]bool{}
+	for _, attr := range n.Attributes {
+		if names[attr.Name] {
+			reasons = append(reasons, fmt.Sprintf("%s: attribute %s occurs more than once", path, attr.Name))
+		}
+		names[attr.Name] = true
+	}
+	seen := map[string]bool{}
+	for i, child := range n.Children {
+		if names[child.Name] && !seen[child.Name] {
+			reasons = append(reasons, fmt.Sprintf("%s: child %s has the name of an attribute", path, child.Name))
+		}
+		if seen[child.Name] && n.Children[i-1].Name != child.Name {
+			reasons = append(reasons, fmt.Sprintf("%s: children named %s are not adjacent, their order is lost", path, child.Name))
+			break
+		}
+		seen[child.Name] = true
+	}
+	return reasons
+}
+
+// attributeLoss returns what is lost when na is written in c.target
+func (c conversion) attributeLoss(na NodeAttribute) []string {
+	if err := na.Validate(); err != nil {
+		return []string{err.Error()}
+	}
+	var reasons []string
+	if len(na.ExtraAttrs) > 0 && c.target != FormatLSX {
+		reasons = append(reasons, fmt.Sprintf("extra XML attributes are not stored in %v files", c.target))
+	}
+
+	switch v := na.Value.(type) {
+	case string:
+		if c.target == FormatLSF && strings.ContainsRune(v, 0) {
+			reasons = append(reasons, "the string is cut at its first null character")
+		}
+		if c.target == FormatLSX && !xmlSafe(v) {
+			reasons = append(reasons, "the string holds characters that can not be written in XML")
+		}
+
+	case Ivec:
+		for _, i := range v {
+			if c.target == FormatLSF && int(int32(i)) != i {
+				reasons = append(reasons, fmt.Sprintf("component %d does not fit in 32 bits", i))
+				break
+			}
+		}
+
+	case Vec:
+		if !float32Exact(v) {
+			reasons = append(reasons, "components lose precision as 32-bit floats")
+		}
+
+	case *Mat:
+		if c.target != FormatLSJ && !float32Exact((*mat.Dense)(v).RawMatrix().Data) {
+			reasons = append(reasons, "components lose precision as 32-bit floats")
+		}
+
+	case TranslatedString:
+		// writeTranslatedString also uses the BG3 layout for the engine version of some early BG3 files
+		bg3Layout := c.version >= VerBG3 || c.engineVersion == 0x4000001d
+		reasons = append(reasons, c.translatedStringLoss(v, bg3Layout)...)
+
+	case TranslatedFSString:
+		reasons = append(reasons, c.translatedFSStringLoss(v)...)
+	}
+	return reasons
+}
+
+// translatedStringLoss returns what is lost of ts in c.target. LSX and LSJ files store the value and the version,
+// LSF files using the BG3 layout store the version and not the value, other LSF files the value and not the version.
+func (c conversion) translatedStringLoss(ts TranslatedString, bg3Layout bool) []string {
+	keepsVersion := c.target != FormatLSF || bg3Layout
+	keepsValue := c.target != FormatLSF || !bg3Layout
+	var reasons []string
+	if ts.Value != "" && !keepsValue {
+		reasons = append(reasons, fmt.Sprintf("the value of translated string %s is not stored", ts.Handle))
+	}
+	if ts.Version != 0 && !keepsVersion {
+		reasons = append(reasons, fmt.Sprintf("the version of translated string %s is not stored", ts.Handle))
+	}
+	return reasons
+}
+
+// translatedFSStringLoss is translatedStringLoss for tfs and the strings of its arguments
+func (c conversion) translatedFSStringLoss(tfs TranslatedFSString) []string {
+	reasons := c.translatedStringLoss(tfs.TranslatedString, c.version >= VerBG3)
+	for _, arg := range tfs.Arguments {
+		reasons = append(reasons, c.translatedFSStringLoss(arg.String)...)
+	}
+	return reasons
+}
+
+// float32Exact reports whether every float in floats is unchanged when stored as a float32
+func float32Exact(floats []float64) bool {
+	for _, f := range floats {
+		if float64(float32(f)) != f && !math.IsNaN(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlSafe reports whether s can be written in an XML attribute without characters being replaced
+func xmlSafe(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r == '\t', r == '\n', r == '\r':
+		case r >= 0x20 && r <= 0xD7FF, r >= 0xE000 && r <= 0xFFFD, r >= 0x10000 && r <= utf8.MaxRune:
+		default:
+			return false
+		}
+	}
+	return true
+}
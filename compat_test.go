@@ -0,0 +1,64 @@
+package lslib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanConvertLossless(t *testing.T) {
+	withAttributes := func(attrs ...NodeAttribute) *Resource {
+		root := NewRegion("Region")
+		root.Attributes = attrs
+		return &Resource{Regions: []*Node{root}}
+	}
+	versioned := NodeAttribute{Name: "Text", Type: DT_TranslatedString, Value: TranslatedString{Handle: "h1", Version: 2}}
+	valued := NodeAttribute{Name: "Text", Type: DT_TranslatedString, Value: TranslatedString{Handle: "h1", Value: "Hello"}}
+	fsVersioned := NodeAttribute{Name: "Text", Type: DT_TranslatedFSString, Value: TranslatedFSString{TranslatedString: TranslatedString{Handle: "h1", Version: 2}}}
+	every := NewRegion("Region")
+	for dt := DT_None + 1; dt <= DT_Max; dt++ {
+		if value, ok := sampleValues[dt]; ok {
+			every.Attributes = append(every.Attributes, NodeAttribute{Name: dt.String(), Type: dt, Value: value})
+		}
+	}
+	collision := namedResource(1)
+	collision.Regions[0].Attributes = []NodeAttribute{{Name: "Node0", Type: DT_Int, Value: int32(1)}}
+
+	tests := []struct {
+		name    string
+		res     *Resource
+		target  Format
+		version FileVersion
+		want    []string
+	}{
+		{"plain LSF", namedResource(3), FormatLSF, VerInitial, nil},
+		{"translated string version in BG3 LSF", withAttributes(versioned), FormatLSF, VerBG3, nil},
+		{"translated string version in older LSF", withAttributes(versioned), FormatLSF, VerExtendedNodes,
+			[]string{"Region: attribute Text: the version of translated string h1 is not stored"}},
+		// The version of translated strings is the only data newer LSF versions store that older ones do not
+		{"translated FS string version in older LSF", withAttributes(fsVersioned), FormatLSF, VerChunkedCompress,
+			[]string{"Region: attribute Text: the version of translated string h1 is not stored"}},
+		{"every data type in the oldest LSF", &Resource{Regions: []*Node{every}}, FormatLSF, VerInitial, nil},
+		{"translated string value in older LSF", withAttributes(valued), FormatLSF, VerInitial, nil},
+		{"translated string value in BG3 LSF", withAttributes(valued), FormatLSF, VerBG3,
+			[]string{"Region: attribute Text: the value of translated string h1 is not stored"}},
+		{"translated string in LSX", withAttributes(valued, NodeAttribute{Name: "Other", Type: DT_TranslatedString, Value: TranslatedString{Handle: "h2", Version: 3}}), FormatLSX, 0, nil},
+		{"translated string in LSJ", withAttributes(valued), FormatLSJ, 0, nil},
+		{"null character in LSF", withAttributes(NodeAttribute{Name: "S", Type: DT_LSString, Value: "a\x00b"}), FormatLSF, VerBG3,
+			[]string{"Region: attribute S: the string is cut at its first null character"}},
+		{"control character in LSX", withAttributes(NodeAttribute{Name: "S", Type: DT_LSString, Value: "a\x01b"}), FormatLSX, 0,
+			[]string{"Region: attribute S: the string holds characters that can not be written in XML"}},
+		{"attribute and child names in LSJ", collision, FormatLSJ, 0,
+			[]string{"Region: child Node0 has the name of an attribute"}},
+		{"attribute and child names in LSX", collision, FormatLSX, 0, nil},
+		{"unsupported version", namedResource(1), FormatLSF, MaxVersion + 1, []string{"LSF version 5: file version is not supported"}},
+		{"LSB", namedResource(1), FormatLSB, 0, []string{"LSB files can not be written"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reasons := CanConvertLossless(tt.res, tt.target, tt.version)
+			if ok != (len(tt.want) == 0) || !reflect.DeepEqual(reasons, tt.want) {
+				t.Errorf("got %v, %q, want %q", ok, reasons, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package lslib
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 type FileVersion uint32
 
@@ -31,6 +34,30 @@ const (
 	MaxVersion = iota
 )
 
+// ParseFileVersion returns the FileVersion of the version field of an LSF header,
+// versions this library can not read return ErrUnsupportedVersion
+func ParseFileVersion(v uint32) (FileVersion, error) {
+	version := FileVersion(v)
+	if version < VerInitial || version > MaxVersion {
+		return 0, fmt.Errorf("LSF version %d: %w", v, ErrUnsupportedVersion)
+	}
+	return version, nil
+}
+
+func (v FileVersion) String() string {
+	switch v {
+	case VerInitial:
+		return "Initial"
+	case VerChunkedCompress:
+		return "ChunkedCompress"
+	case VerExtendedNodes:
+		return "ExtendedNodes"
+	case VerBG3:
+		return "BG3"
+	}
+	return fmt.Sprintf("FileVersion(%d)", uint32(v))
+}
+
 type CompressionMethod int
 
 const (
@@ -52,4 +79,22 @@ var (
 	ErrVectorTooBig    = errors.New("the vector is too big cannot marshal to an xml element")
 	ErrInvalidNameKey  = errors.New("invalid name key")
 	ErrKeyDoesNotMatch = errors.New("key for this node does not match")
+	ErrNilUUID         = errors.New("uuid attribute is not set")
+	ErrNodeCycle       = errors.New("node would become its own descendant")
+	ErrNameTooLong     = errors.New("name is longer than the LSF name table allows")
+	ErrNameTableFull   = errors.New("LSF name table bucket is full")
+	ErrWriterClosed    = errors.New("writer is closed")
+	ErrIndexOutOfRange = errors.New("vector has no such component")
+	ErrUnknownDataType = errors.New("unknown data type")
+	ErrMergeConflict   = errors.New("localization entries with the same handle have different text")
+	ErrStopScan        = errors.New("stop scan")
+
+	ErrNotNumeric         = errors.New("value is not a number")
+	ErrDimensionMismatch  = errors.New("value does not have the dimensions of its type")
+	ErrUnsupportedVersion = errors.New("file version is not supported")
+	ErrUnknownFormat      = errors.New("data is not in a known format")
+	ErrMissingType        = errors.New("attribute element has no type")
+	ErrSchemaMismatch     = errors.New("resource does not match the schema")
+	ErrNodeNotFound       = errors.New("node not found")
+	ErrDivisionByZero     = errors.New("division by zero")
 )
@@ -0,0 +1,37 @@
+package lslib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFileVersion(t *testing.T) {
+	tests := []struct {
+		v       uint32
+		want    FileVersion
+		name    string
+		wantErr error
+	}{
+		{1, VerInitial, "Initial", nil},
+		{2, VerChunkedCompress, "ChunkedCompress", nil},
+		{3, VerExtendedNodes, "ExtendedNodes", nil},
+		{4, VerBG3, "BG3", nil},
+		{0, 0, "FileVersion(0)", ErrUnsupportedVersion},
+		{uint32(MaxVersion) + 1, 0, "FileVersion(0)", ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		got, err := ParseFileVersion(tt.v)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%d: got %v, %v, want %v, %v", tt.v, got, err, tt.want, tt.wantErr)
+		}
+		if got.String() != tt.name {
+			t.Errorf("%d: got name %q, want %q", tt.v, got.String(), tt.name)
+		}
+	}
+	if got := FileVersion(MaxVersion + 1).String(); got != "FileVersion(5)" {
+		t.Errorf("got name %q for an unknown version", got)
+	}
+	if MaxVersion != VerBG3 {
+		t.Errorf("MaxVersion is %v, add the new version to this test", MaxVersion)
+	}
+}
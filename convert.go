@@ -0,0 +1,115 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ConvertLSFToLSX converts the LSF file in r to an LSX document written to w. The output is the same as
+// reading r with ReadLSF and writing it with WriteLSX, but the nodes are written as they are read without
+// building the tree of nodes. The decompressed sections of the LSF file are still held in memory,
+// r is read into memory first if it is not an io.ReadSeeker.
+// The nodes of r must be stored depth first, as Larian's tools and WriteLSF store them.
+func ConvertLSFToLSX(w io.Writer, r io.Reader) error {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		rs = bytes.NewReader(data)
+	}
+	tables, err := readLSFTables(rs)
+	if err != nil {
+		return err
+	}
+
+	var (
+		buf = &bytes.Buffer{}
+		e   = xml.NewEncoder(buf)
+		nw  = NewNodeWriter(e)
+		// open holds the indexes of the nodes from the region root to the current node
+		open []int
+	)
+	e.Indent("", "\t")
+	// flush writes the encoded elements to w in the formatting of LSXWriter. It is only called between
+	// complete elements and before a start element, so an empty element is never split across two calls.
+	flush := func() error {
+		err := e.Flush()
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, formatLSX(buf.Bytes(), " />"))
+		buf.Reset()
+		return err
+	}
+	end := func() error {
+		err := nw.WriteNodeEnd()
+		if err != nil {
+			return err
+		}
+		open = open[:len(open)-1]
+		if len(open) == 0 {
+			return nw.WriteRegionEnd()
+		}
+		return nil
+	}
+
+	_, err = io.WriteString(w, strings.ToLower(xml.Header))
+	if err != nil {
+		return err
+	}
+	save := xml.StartElement{Name: xml.Name{Local: "save"}}
+	err = e.EncodeToken(save)
+	if err == nil {
+		err = e.EncodeElement(tables.hdr.Metadata(), xml.StartElement{Name: xml.Name{Local: "version"}})
+	}
+	if err != nil {
+		return err
+	}
+	err = tables.walk(func(i int, ni NodeInfo, name string) error {
+		for len(open) > 0 && open[len(open)-1] != ni.ParentIndex {
+			err := end()
+			if err != nil {
+				return err
+			}
+		}
+		err := flush()
+		if err != nil {
+			return err
+		}
+		if ni.ParentIndex == -1 {
+			err = nw.WriteRegionStart(name)
+		} else if len(open) == 0 {
+			err = fmt.Errorf("node %s: node %d is not stored depth first", name, i)
+		}
+		if err != nil {
+			return err
+		}
+		open = append(open, i)
+		return nw.WriteNodeStart(name)
+	}, nw.WriteAttribute)
+	if err != nil {
+		return err
+	}
+	for len(open) > 0 {
+		err = end()
+		if err != nil {
+			return err
+		}
+	}
+	err = e.EncodeToken(save.End())
+	if err != nil {
+		return err
+	}
+	err = flush()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
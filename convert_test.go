@@ -0,0 +1,57 @@
+package lslib
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func nestedResource() *Resource {
+	root := NewRegion("Templates")
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		if value, ok := sampleValues[dt]; ok {
+			root.Attributes = append(root.Attributes, NodeAttribute{Name: dt.String(), Type: dt, Value: value})
+		}
+	}
+	for i := 0; i < 3; i++ {
+		item := &Node{Name: "Item", Parent: root}
+		item.Attributes = []NodeAttribute{{Name: "Name", Type: DT_LSString, Value: fmt.Sprintf("Bob's <item> %d", i)}}
+		empty := &Node{Name: "Empty", Parent: item}
+		rune := &Node{Name: "Rune", Parent: item}
+		rune.Children = []*Node{{Name: "Glow", Parent: rune, Attributes: []NodeAttribute{{Name: "Color", Type: DT_IVec4, Value: Ivec{255, 0, 0, 255}}}}}
+		item.Children = []*Node{empty, rune}
+		root.Children = append(root.Children, item)
+	}
+	root.Children = append(root.Children, &Node{Name: "Last", Parent: root})
+	return &Resource{Regions: []*Node{root, NewRegion("Empty"), namedResource(3).Regions[0]}}
+}
+
+func TestConvertLSFToLSX(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *Resource
+	}{
+		{"no regions", &Resource{}},
+		{"flat", namedResource(50)},
+		{"nested", nestedResource()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := lsfFile(t, tt.res, VerBG3)
+			res, err := ReadLSF(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want, got bytes.Buffer
+			if err := WriteLSX(&want, res); err != nil {
+				t.Fatal(err)
+			}
+			if err := ConvertLSFToLSX(&got, bytes.NewBuffer(data)); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("got\n%s\nwant\n%s", got.String(), want.String())
+			}
+		})
+	}
+}
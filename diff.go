@@ -0,0 +1,81 @@
+package lslib
+
+import "fmt"
+
+// DiffOp is an operation transforming one attribute slice into another
+type DiffOp int
+
+const (
+	DiffAdd DiffOp = iota
+	DiffRemove
+	DiffModify
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffAdd:
+		return "Add"
+	case DiffRemove:
+		return "Remove"
+	case DiffModify:
+		return "Modify"
+	}
+	return fmt.Sprintf("DiffOp(%d)", int(op))
+}
+
+// AttributeDiff is a single change between two attribute slices. Old is unset for DiffAdd and New is unset for DiffRemove.
+type AttributeDiff struct {
+	Op  DiffOp
+	Old NodeAttribute
+	New NodeAttribute
+}
+
+// DiffAttributeSlices returns the operations transforming a into b in order. Attributes are matched by name
+// using the longest common subsequence of both slices, matched attributes that are not equal are modified.
+func DiffAttributeSlices(a, b []NodeAttribute) []AttributeDiff {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i].Name == b[j].Name:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var (
+		diff []AttributeDiff
+		i, j int
+	)
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i].Name == b[j].Name:
+			if !a[i].Equal(b[j]) {
+				diff = append(diff, AttributeDiff{Op: DiffModify, Old: a[i], New: b[j]})
+			}
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, AttributeDiff{Op: DiffRemove, Old: a[i]})
+			i++
+		default:
+			diff = append(diff, AttributeDiff{Op: DiffAdd, New: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, AttributeDiff{Op: DiffRemove, Old: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, AttributeDiff{Op: DiffAdd, New: b[j]})
+	}
+	return diff
+}
@@ -0,0 +1,56 @@
+package lslib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiffAttributeSlices(t *testing.T) {
+	attr := func(name string, value int32) NodeAttribute {
+		return NodeAttribute{Name: name, Type: DT_Int, Value: value}
+	}
+	tests := []struct {
+		name string
+		a, b []NodeAttribute
+		want []string
+	}{
+		{"equal", []NodeAttribute{attr("A", 1), attr("B", 2)}, []NodeAttribute{attr("A", 1), attr("B", 2)}, nil},
+		{"add", []NodeAttribute{attr("A", 1)}, []NodeAttribute{attr("A", 1), attr("B", 2)}, []string{"Add B"}},
+		{"remove", []NodeAttribute{attr("A", 1), attr("B", 2)}, []NodeAttribute{attr("B", 2)}, []string{"Remove A"}},
+		{"modify", []NodeAttribute{attr("A", 1)}, []NodeAttribute{attr("A", 3)}, []string{"Modify A"}},
+		{"move", []NodeAttribute{attr("A", 1), attr("B", 2), attr("C", 3)}, []NodeAttribute{attr("B", 2), attr("C", 3), attr("A", 1)}, []string{"Remove A", "Add A"}},
+		{"empty", nil, []NodeAttribute{attr("A", 1)}, []string{"Add A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range DiffAttributeSlices(tt.a, tt.b) {
+				name := d.New.Name
+				if d.Op == DiffRemove {
+					name = d.Old.Name
+				}
+				got = append(got, d.Op.String()+" "+name)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffOpString(t *testing.T) {
+	tests := []struct {
+		op   DiffOp
+		want string
+	}{
+		{DiffAdd, "Add"},
+		{DiffRemove, "Remove"},
+		{DiffModify, "Modify"},
+		{DiffOp(7), "DiffOp(7)"},
+	}
+	for _, tt := range tests {
+		if got := tt.op.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
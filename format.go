@@ -0,0 +1,63 @@
+package lslib
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Format is a file format for resources
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatLSF
+	FormatLSX
+	FormatLSJ
+	FormatLSB
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatLSF:
+		return "LSF"
+	case FormatLSX:
+		return "LSX"
+	case FormatLSJ:
+		return "LSJ"
+	case FormatLSB:
+		return "LSB"
+	}
+	return "Unknown"
+}
+
+var (
+	// Signatures of LSB files, older games use 0x40000000 and BG3 uses LSFM
+	lsbSignature    = []byte{0x00, 0x00, 0x00, 0x40}
+	lsbSignatureBG3 = []byte("LSFM")
+)
+
+// DetectFormat returns the format of the resource in data from its leading bytes. Binary formats are recognized by
+// their signature, LSX and LSJ by their first character after an optional byte order mark and whitespace.
+// It returns FormatUnknown and ErrUnknownFormat if none match.
+func DetectFormat(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, LSFSignature[:]):
+		return FormatLSF, nil
+	case bytes.HasPrefix(data, lsbSignature), bytes.HasPrefix(data, lsbSignatureBG3):
+		return FormatLSB, nil
+	}
+
+	text := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\ufeff")), " \t\r\n")
+	switch {
+	case bytes.HasPrefix(text, []byte("<")):
+		return FormatLSX, nil
+	case bytes.HasPrefix(text, []byte("{")):
+		return FormatLSJ, nil
+	}
+
+	prefix := data
+	if len(prefix) > 4 {
+		prefix = prefix[:4]
+	}
+	return FormatUnknown, fmt.Errorf("leading bytes % x: %w", prefix, ErrUnknownFormat)
+}
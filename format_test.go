@@ -0,0 +1,36 @@
+package lslib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{"LSF", []byte("LSOF\x06\x00\x00\x00"), FormatLSF},
+		{"LSB", []byte{0x00, 0x00, 0x00, 0x40, 0x10, 0x00}, FormatLSB},
+		{"LSB BG3", []byte("LSFM\x00\x00"), FormatLSB},
+		{"LSX", []byte(`<?xml version="1.0" encoding="utf-8"?>`), FormatLSX},
+		{"LSX with byte order mark", []byte("\ufeff<save>"), FormatLSX},
+		{"LSJ", []byte("{\n\t\"save\": {"), FormatLSJ},
+		{"LSJ after whitespace", []byte(" \r\n{}"), FormatLSJ},
+		{"empty", nil, FormatUnknown},
+		{"text", []byte("hello"), FormatUnknown},
+		{"short signature", []byte("LSO"), FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(tt.data)
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if (tt.want == FormatUnknown) != errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("got error %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/lordwelch/golslib
 
-go 1.15
+go 1.18
 
 replace github.com/pierrec/lz4/v4 v4.1.1 => ./lz4
 
@@ -11,3 +11,8 @@ require (
 	github.com/pierrec/lz4/v4 v4.1.1
 	gonum.org/v1/gonum v0.8.1
 )
+
+require (
+	github.com/go-logfmt/logfmt v0.5.0 // indirect
+	github.com/kr/text v0.1.0 // indirect
+)
@@ -0,0 +1,111 @@
+package lslib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LocalizationEntry is a translated text of a LOCA file
+type LocalizationEntry struct {
+	Key     string
+	Version uint16
+	Text    string
+}
+
+// ConflictPolicy decides which entry MergeLOCA keeps when entries with the same handle have different text
+type ConflictPolicy int
+
+const (
+	// ConflictKeepFirst keeps the entry from the earliest file
+	ConflictKeepFirst ConflictPolicy = iota
+	// ConflictKeepLast keeps the entry from the latest file, later files override earlier ones
+	ConflictKeepLast
+	// ConflictKeepHighestVersion keeps the entry with the highest version, the latest file wins a tie
+	ConflictKeepHighestVersion
+	// ConflictError makes MergeLOCA fail with ErrMergeConflict
+	ConflictError
+)
+
+func (cp ConflictPolicy) String() string {
+	switch cp {
+	case ConflictKeepFirst:
+		return "KeepFirst"
+	case ConflictKeepLast:
+		return "KeepLast"
+	case ConflictKeepHighestVersion:
+		return "KeepHighestVersion"
+	case ConflictError:
+		return "Error"
+	}
+	return fmt.Sprintf("ConflictPolicy(%d)", int(cp))
+}
+
+// MergeConflict lists the different texts found for a handle, in the order of the files they came from
+type MergeConflict struct {
+	Handle string
+	Texts  []string
+}
+
+// MergeLOCA combines the entries of several LOCA files into one list with a single entry per handle.
+//
+// Entries are returned in the order their handle first appears. Duplicates with the same text are merged silently,
+// if the texts differ the entry kept is chosen by conflict and the handle is reported in the returned conflicts.
+// With ConflictError the conflicts are returned together with an error wrapping ErrMergeConflict.
+func MergeLOCA(bases [][]LocalizationEntry, conflict ConflictPolicy) ([]LocalizationEntry, []MergeConflict, error) {
+	if conflict < ConflictKeepFirst || conflict > ConflictError {
+		return nil, nil, fmt.Errorf("invalid conflict policy %v", conflict)
+	}
+	var (
+		merged     []LocalizationEntry
+		conflicts  []MergeConflict
+		entries    = make(map[string]int)
+		conflicted = make(map[string]int)
+	)
+	for _, base := range bases {
+		for _, entry := range base {
+			i, ok := entries[entry.Key]
+			if !ok {
+				entries[entry.Key] = len(merged)
+				merged = append(merged, entry)
+				continue
+			}
+			kept := &merged[i]
+			if kept.Text != entry.Text {
+				c, ok := conflicted[entry.Key]
+				if !ok {
+					c = len(conflicts)
+					conflicted[entry.Key] = c
+					conflicts = append(conflicts, MergeConflict{Handle: entry.Key, Texts: []string{kept.Text}})
+				}
+				if !contains(conflicts[c].Texts, entry.Text) {
+					conflicts[c].Texts = append(conflicts[c].Texts, entry.Text)
+				}
+			}
+			switch conflict {
+			case ConflictKeepLast:
+				*kept = entry
+			case ConflictKeepHighestVersion:
+				if entry.Version >= kept.Version {
+					*kept = entry
+				}
+			}
+		}
+	}
+	if conflict == ConflictError && len(conflicts) > 0 {
+		handles := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			handles[i] = c.Handle
+		}
+		return nil, conflicts, fmt.Errorf("%w: %s", ErrMergeConflict, strings.Join(handles, ", "))
+	}
+	return merged, conflicts, nil
+}
+
+func contains(s []string, v string) bool {
+	for _, str := range s {
+		if str == v {
+			return true
+		}
+	}
+	return false
+}
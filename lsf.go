@@ -1,10 +1,12 @@
 package lslib
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strconv"
 	"strings"
 
@@ -249,6 +251,42 @@ func (lsfh *LSFHeader) Read(r io.ReadSeeker) error {
 	return nil
 }
 
+// lsfHeaderSize is the size in bytes of the header read by LSFHeader.Read
+const lsfHeaderSize = 52
+
+// ReadLSFHeader reads only the header of an LSF file from r, nothing after the header is read. The header holds the
+// file and engine versions and the compression of the sections; LSF files do not record a timestamp.
+func ReadLSFHeader(r io.Reader) (*LSFHeader, error) {
+	var buf [lsfHeaderSize]byte
+	_, err := io.ReadFull(r, buf[:])
+	hdr := &LSFHeader{}
+	copy(hdr.Signature[:], buf[:])
+	if hdr.Signature != LSFSignature {
+		return nil, HeaderError{LSFSignature[:], hdr.Signature[:]}
+	}
+	if err != nil {
+		return nil, truncated(err)
+	}
+	err = hdr.Read(bytes.NewReader(buf[:]))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseFileVersion(uint32(hdr.Version)); err != nil {
+		return nil, err
+	}
+	return hdr, nil
+}
+
+// Metadata returns the engine version stored in the header
+func (lsfh LSFHeader) Metadata() LSMetadata {
+	return LSMetadata{
+		MajorVersion: (lsfh.EngineVersion & 0xf0000000) >> 28,
+		MinorVersion: (lsfh.EngineVersion & 0xf000000) >> 24,
+		Revision:     (lsfh.EngineVersion & 0xff0000) >> 16,
+		BuildNumber:  (lsfh.EngineVersion & 0xffff),
+	}
+}
+
 func (lsfh LSFHeader) IsCompressed() bool {
 	return CompressionFlagsToMethod(lsfh.CompressionFlags) != CMNone && CompressionFlagsToMethod(lsfh.CompressionFlags) != CMInvalid
 }
@@ -281,11 +319,16 @@ type NodeEntry struct {
 	NextSiblingIndex int32
 }
 
+// Read reads a node entry, io.EOF is only returned if r ends before the entry
 func (ne *NodeEntry) Read(r io.ReadSeeker) error {
+	var err error
+	start, _ := r.Seek(0, io.SeekCurrent)
 	if ne.Long {
-		return ne.readLong(r)
+		err = ne.readLong(r)
+	} else {
+		err = ne.readShort(r)
 	}
-	return ne.readShort(r)
+	return entryEOF(r, start, err)
 }
 
 func (ne *NodeEntry) readShort(r io.ReadSeeker) error {
@@ -440,11 +483,24 @@ type AttributeEntry struct {
 	Offset uint32
 }
 
+// Read reads an attribute entry, io.EOF is only returned if r ends before the entry
 func (ae *AttributeEntry) Read(r io.ReadSeeker) error {
+	var err error
+	start, _ := r.Seek(0, io.SeekCurrent)
 	if ae.Long {
-		return ae.readLong(r)
+		err = ae.readLong(r)
+	} else {
+		err = ae.readShort(r)
 	}
-	return ae.readShort(r)
+	return entryEOF(r, start, err)
+}
+
+// entryEOF returns io.ErrUnexpectedEOF instead of io.EOF if part of an entry starting at start was read
+func entryEOF(r io.Seeker, start int64, err error) error {
+	if pos, _ := r.Seek(0, io.SeekCurrent); err == io.EOF && pos != start {
+		return io.ErrUnexpectedEOF
+	}
+	return err
 }
 
 func (ae *AttributeEntry) readShort(r io.ReadSeeker) error {
@@ -577,7 +633,7 @@ type AttributeInfo struct {
 	/// /summary
 	Length uint
 	/// summary
-	/// Absolute position of attribute data in the values section
+	/// Position of attribute data from the start of the values section
 	/// /summary
 	DataOffset uint
 	/// summary
@@ -587,8 +643,30 @@ type AttributeInfo struct {
 	NextAttributeIndex int
 }
 
+// LSFReader reads LSF files, reusing its buffers between reads
 type LSFReader struct {
-	data *bufio.Reader
+	// data holds the file when it is read from an io.Reader that can not seek
+	data bytes.Buffer
+}
+
+// ReadInto reads an LSF file from r into res, replacing its contents. The Regions slice of res is reused,
+// r is read into a buffer kept by lr if it is not an io.ReadSeeker.
+func (lr *LSFReader) ReadInto(r io.Reader, res *Resource) error {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		lr.data.Reset()
+		_, err := lr.data.ReadFrom(r)
+		if err != nil {
+			return err
+		}
+		rs = bytes.NewReader(lr.data.Bytes())
+	}
+
+	for i := range res.Regions {
+		res.Regions[i] = nil
+	}
+	*res = Resource{Regions: res.Regions[:0]}
+	return readLSF(rs, res)
 }
 
 // extract to lsf package
@@ -619,7 +697,10 @@ func ReadNames(r io.ReadSeeker) ([][]string, error) {
 		var numStrings uint16
 
 		err = binary.Read(r, binary.LittleEndian, &numStrings)
-		n = 4
+		n = 2
+		if err != nil {
+			return nil, err
+		}
 		l.Log("member", "numStrings", "read", n, "start position", pos, "value", numStrings)
 		pos += int64(n)
 
@@ -639,7 +720,7 @@ func ReadNames(r io.ReadSeeker) ([][]string, error) {
 
 			name = make([]byte, nameLen)
 
-			n, err = r.Read(name)
+			n, err = io.ReadFull(r, name)
 			if err != nil {
 				return nil, err
 			}
@@ -683,6 +764,9 @@ func readNodeInfo(r io.ReadSeeker, longNodes bool) ([]NodeInfo, error) {
 		nodes = append(nodes, node)
 		index++
 	}
+	if err == io.EOF {
+		err = nil
+	}
 	return nodes[:len(nodes)-1], err
 }
 
@@ -690,7 +774,7 @@ func readNodeInfo(r io.ReadSeeker, longNodes bool) ([]NodeInfo, error) {
 /// Reads the attribute headers for the LSOF resource
 /// </summary>
 /// <param name="s">Stream to read the attribute headers from</param>
-func readAttributeInfo(r io.ReadSeeker, long bool) []AttributeInfo {
+func readAttributeInfo(r io.ReadSeeker, long bool) ([]AttributeInfo, error) {
 	// var rawAttributes = new List<AttributeEntryV2>();
 
 	var (
@@ -714,10 +798,15 @@ func readAttributeInfo(r io.ReadSeeker, long bool) []AttributeInfo {
 			nextAttrIndex = int(attribute.NextAttributeIndex)
 		}
 
+		typeID, err := AttributeTypeByID(uint8(attribute.TypeID()))
+		if err != nil {
+			return attributes, fmt.Errorf("attribute %d: %w", index, err)
+		}
+
 		resolved := AttributeInfo{
 			NameIndex:          attribute.NameIndex(),
 			NameOffset:         attribute.NameOffset(),
-			TypeId:             attribute.TypeID(),
+			TypeId:             typeID,
 			Length:             uint(attribute.Len()),
 			DataOffset:         dataOffset,
 			NextAttributeIndex: nextAttrIndex,
@@ -738,7 +827,10 @@ func readAttributeInfo(r io.ReadSeeker, long bool) []AttributeInfo {
 		attributes = append(attributes, resolved)
 		index++
 	}
-	return attributes
+	if err == io.EOF {
+		err = nil
+	}
+	return attributes, err
 	// }
 
 	// Console.WriteLine(" ----- DUMP OF ATTRIBUTE REFERENCES -----");
@@ -772,29 +864,125 @@ func (he HeaderError) Error() string {
 	return fmt.Sprintf("Invalid LSF signature; expected %v, got %v", he.Expected, he.Got)
 }
 
-func ReadLSF(r io.ReadSeeker) (Resource, error) {
+// LSFParseError records where in an LSF file reading failed. Offset is the position in the file,
+// or the position in the decompressed section if the file is compressed.
+type LSFParseError struct {
+	Offset int64
+	Phase  string
+	Cause  error
+}
+
+func (pe LSFParseError) Error() string {
+	return fmt.Sprintf("reading LSF %s failed at offset %d: %v", pe.Phase, pe.Offset, pe.Cause)
+}
+
+func (pe LSFParseError) Unwrap() error {
+	return pe.Cause
+}
+
+func newLSFParseError(r io.Seeker, phase string, err error) error {
+	offset, _ := r.Seek(0, io.SeekCurrent)
+	return LSFParseError{
+		Offset: offset,
+		Phase:  phase,
+		Cause:  err,
+	}
+}
+
+// readSection reads a section of sizeOnDisk bytes from r and decompresses it if the file is compressed.
+// A section that ends early is reported as an LSFParseError for the section wrapping io.ErrUnexpectedEOF.
+func (lsfh LSFHeader) readSection(r io.ReadSeeker, section string, sizeOnDisk, uncompressedSize uint32, chunked bool) (io.ReadSeeker, error) {
+	data := make([]byte, sizeOnDisk)
+	_, err := io.ReadFull(r, data)
+	if err != nil {
+		return nil, newLSFParseError(r, section, truncated(err))
+	}
+	if !lsfh.IsCompressed() || sizeOnDisk == 0 {
+		return bytes.NewReader(data), nil
+	}
+	data, err = Decompress(data, int(uncompressedSize), CompressionFlagsToMethod(lsfh.CompressionFlags), chunked)
+	if err != nil {
+		return nil, newLSFParseError(r, section, err)
+	}
+	return bytes.NewReader(data), nil
+}
+
+// truncated converts io.EOF to io.ErrUnexpectedEOF, for reads of data whose size is already known
+func truncated(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ReadLSF reads an LSF file from r, r is read into memory first if it is not an io.ReadSeeker
+func ReadLSF(r io.Reader) (*Resource, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		rs = bytes.NewReader(data)
+	}
+	res := &Resource{}
+	err := readLSF(rs, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// lsfTables holds the sections of an LSF file that describe its nodes and attributes
+type lsfTables struct {
+	hdr           *LSFHeader
+	names         [][]string
+	nodeInfo      []NodeInfo
+	attributeInfo []AttributeInfo
+	// values is the decompressed values section, attribute data offsets are relative to its start
+	values io.ReadSeeker
+}
+
+// readLSF reads an LSF file from r, appending its regions to res
+func readLSF(r io.ReadSeeker, res *Resource) error {
 	var (
-		err error
-		/// summary
-		/// Static string hash map
-		/// /summary
-		names [][]string
-		/// summary
-		/// Preprocessed list of nodes (structures)
-		/// /summary
-		nodeInfo []NodeInfo
-		/// summary
-		/// Preprocessed list of node attributes
-		/// /summary
-		attributeInfo []AttributeInfo
 		/// summary
 		/// Node instances
 		/// /summary
 		nodeInstances []*Node
 	)
+	tables, err := readLSFTables(r)
+	if err != nil {
+		return err
+	}
+	hdr := tables.hdr
+
+	nodeInstances, err = ReadRegions(tables.values, tables.names, tables.nodeInfo, tables.attributeInfo, hdr.Version, hdr.EngineVersion)
+	if err != nil {
+		return newLSFParseError(tables.values, "values", err)
+	}
+	for _, v := range nodeInstances {
+		if v.Parent == nil {
+			res.Regions = append(res.Regions, v)
+		}
+	}
+
+	res.Metadata = hdr.Metadata()
+
+	// pretty.Log(res)
+	return nil
+
+}
+
+// readLSFTables reads the header of an LSF file and decompresses its sections
+func readLSFTables(r io.ReadSeeker) (*lsfTables, error) {
+	var (
+		err    error
+		tables = &lsfTables{}
+	)
 	var (
-		l         log.Logger
-		pos, npos int64
+		l   log.Logger
+		pos int64
 		// n   int
 	)
 	l = log.With(Logger, "component", "LS converter", "file type", "lsf", "part", "file")
@@ -802,138 +990,165 @@ func ReadLSF(r io.ReadSeeker) (Resource, error) {
 	l.Log("member", "LSF header", "start position", pos)
 
 	hdr := &LSFHeader{}
+	tables.hdr = hdr
 	err = hdr.Read(r)
+	if err != nil && hdr.Signature == LSFSignature {
+		return nil, newLSFParseError(r, "header", truncated(err))
+	}
 	if err != nil || (hdr.Signature != LSFSignature) {
-		return Resource{}, HeaderError{LSFSignature[:], hdr.Signature[:]}
+		return nil, HeaderError{LSFSignature[:], hdr.Signature[:]}
 	}
 
-	if hdr.Version < VerInitial || hdr.Version > MaxVersion {
-		return Resource{}, fmt.Errorf("LSF version %v is not supported", hdr.Version)
+	if _, err := ParseFileVersion(uint32(hdr.Version)); err != nil {
+		return nil, err
 	}
 
-	isCompressed := CompressionFlagsToMethod(hdr.CompressionFlags) != CMNone && CompressionFlagsToMethod(hdr.CompressionFlags) != CMInvalid
+	chunked := hdr.Version >= VerChunkedCompress
 
-	pos, err = r.Seek(0, io.SeekCurrent)
+	pos, _ = r.Seek(0, io.SeekCurrent)
 	l.Log("member", "LSF names", "start position", pos)
+	uncompressed, err := hdr.readSection(r, "names", hdr.StringsSizeOnDisk, hdr.StringsUncompressedSize, false)
+	if err != nil {
+		return nil, err
+	}
 	if hdr.StringsSizeOnDisk > 0 || hdr.StringsUncompressedSize > 0 {
-		var (
-			uncompressed = LimitReadSeeker(r, int64(hdr.StringsSizeOnDisk))
-		)
-
-		if isCompressed {
-			uncompressed = Decompress(uncompressed, int(hdr.StringsUncompressedSize), hdr.CompressionFlags, false)
-		}
-
-		// using (var nodesFile = new FileStream("names.bin", FileMode.Create, FileAccess.Write))
-		// {
-		//     nodesFile.Write(uncompressed, 0, uncompressed.Length);
-		// }
-
-		names, err = ReadNames(uncompressed)
-		// pretty.Log(len(names), names)
-		if err != nil && err != io.EOF {
-			return Resource{}, err
+		tables.names, err = ReadNames(uncompressed)
+		if err != nil {
+			return nil, newLSFParseError(uncompressed, "names", truncated(err))
 		}
 	}
 
-	npos, err = r.Seek(0, io.SeekCurrent)
-	l.Log("member", "LSF nodes", "start position", npos)
-	if npos != pos+int64(hdr.StringsSizeOnDisk) {
-		l.Log("member", "LSF nodes", "msg", "seeking to correct offset", "current", npos, "wanted", pos+int64(hdr.StringsSizeOnDisk))
-		pos, _ = r.Seek((pos+int64(hdr.StringsSizeOnDisk))-npos, io.SeekCurrent)
-	} else {
-		pos = npos
+	pos, _ = r.Seek(0, io.SeekCurrent)
+	l.Log("member", "LSF nodes", "start position", pos)
+	uncompressed, err = hdr.readSection(r, "nodes", hdr.NodesSizeOnDisk, hdr.NodesUncompressedSize, chunked)
+	if err != nil {
+		return nil, err
 	}
 	if hdr.NodesSizeOnDisk > 0 || hdr.NodesUncompressedSize > 0 {
-		var (
-			uncompressed = LimitReadSeeker(r, int64(hdr.NodesSizeOnDisk))
-		)
-		if isCompressed {
-			uncompressed = Decompress(uncompressed, int(hdr.NodesUncompressedSize), hdr.CompressionFlags, hdr.Version >= VerChunkedCompress)
-		}
-
-		// using (var nodesFile = new FileStream("nodes.bin", FileMode.Create, FileAccess.Write))
-		// {
-		//     nodesFile.Write(uncompressed, 0, uncompressed.Length);
-		// }
-
 		longNodes := hdr.Version >= VerExtendedNodes && hdr.Extended == 1
-		nodeInfo, err = readNodeInfo(uncompressed, longNodes)
-		// pretty.Log(err, nodeInfo)
-		// logger.Printf("region 1 name: %v", names[nodeInfo[0].NameIndex])
-		if err != nil && err != io.EOF {
-			return Resource{}, err
+		tables.nodeInfo, err = readNodeInfo(uncompressed, longNodes)
+		if err != nil {
+			return nil, newLSFParseError(uncompressed, "nodes", err)
 		}
 	}
 
-	npos, err = r.Seek(0, io.SeekCurrent)
-	l.Log("member", "LSF attributes", "start position", npos)
-	if npos != pos+int64(hdr.NodesSizeOnDisk) {
-		l.Log("msg", "seeking to correct offset", "current", npos, "wanted", pos+int64(hdr.NodesSizeOnDisk))
-		pos, _ = r.Seek((pos+int64(hdr.NodesSizeOnDisk))-npos, io.SeekCurrent)
-	} else {
-		pos = npos
+	pos, _ = r.Seek(0, io.SeekCurrent)
+	l.Log("member", "LSF attributes", "start position", pos)
+	uncompressed, err = hdr.readSection(r, "attributes", hdr.AttributesSizeOnDisk, hdr.AttributesUncompressedSize, chunked)
+	if err != nil {
+		return nil, err
 	}
 	if hdr.AttributesSizeOnDisk > 0 || hdr.AttributesUncompressedSize > 0 {
-		var (
-			uncompressed io.ReadSeeker = LimitReadSeeker(r, int64(hdr.AttributesSizeOnDisk))
-		)
-		if isCompressed {
-			uncompressed = Decompress(uncompressed, int(hdr.AttributesUncompressedSize), hdr.CompressionFlags, hdr.Version >= VerChunkedCompress)
-		}
-
-		// using (var attributesFile = new FileStream("attributes.bin", FileMode.Create, FileAccess.Write))
-		// {
-		//     attributesFile.Write(uncompressed, 0, uncompressed.Length);
-		// }
-
 		longAttributes := hdr.Version >= VerExtendedNodes && hdr.Extended == 1
-		attributeInfo = readAttributeInfo(uncompressed, longAttributes)
-		// logger.Printf("attribute 1 name: %v", names[attributeInfo[0].NameIndex])
-		// pretty.Log(attributeInfo)
+		tables.attributeInfo, err = readAttributeInfo(uncompressed, longAttributes)
+		if err != nil {
+			return nil, newLSFParseError(uncompressed, "attributes", err)
+		}
 	}
 
-	npos, err = r.Seek(0, io.SeekCurrent)
-	l.Log("member", "LSF values", "start position", npos)
-	if npos != pos+int64(hdr.AttributesSizeOnDisk) {
-		l.Log("msg", "seeking to correct offset", "current", npos, "wanted", pos+int64(hdr.AttributesSizeOnDisk))
-		pos, _ = r.Seek((pos+int64(hdr.AttributesSizeOnDisk))-npos, io.SeekCurrent)
-	} else {
-		pos = npos
+	pos, _ = r.Seek(0, io.SeekCurrent)
+	l.Log("member", "LSF values", "start position", pos)
+	tables.values, err = hdr.readSection(r, "values", hdr.ValuesSizeOnDisk, hdr.ValuesUncompressedSize, chunked)
+	if err != nil {
+		return nil, err
 	}
-	var (
-		uncompressed io.ReadSeeker = LimitReadSeeker(r, int64(hdr.ValuesSizeOnDisk))
-	)
-	if hdr.ValuesSizeOnDisk > 0 || hdr.ValuesUncompressedSize > 0 {
-		if isCompressed {
-			uncompressed = Decompress(r, int(hdr.ValuesUncompressedSize), hdr.CompressionFlags, hdr.Version >= VerChunkedCompress)
+	return tables, nil
+}
+
+// ScanLSF calls visit for each attribute of an LSF file, in the order of the nodes that hold them, without building
+// the tree of nodes. path holds the names of the region root down to the node holding na, it is only valid during
+// the call to visit. If visit returns ErrStopScan the scan stops and ScanLSF returns nil, any other error stops
+// the scan and is returned.
+func ScanLSF(r io.Reader, visit func(path []string, na NodeAttribute) error) error {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
 		}
+		rs = bytes.NewReader(data)
 	}
-
-	res := Resource{}
-	valueStart, _ = uncompressed.Seek(0, io.SeekCurrent)
-	nodeInstances, err = ReadRegions(uncompressed, names, nodeInfo, attributeInfo, hdr.Version, hdr.EngineVersion)
+	tables, err := readLSFTables(rs)
 	if err != nil {
-		return res, err
+		return err
 	}
-	for _, v := range nodeInstances {
-		if v.Parent == nil {
-			res.Regions = append(res.Regions, v)
-		}
+	err = tables.scan(visit)
+	if errors.Is(err, ErrStopScan) {
+		return nil
 	}
+	return err
+}
 
-	res.Metadata.MajorVersion = (hdr.EngineVersion & 0xf0000000) >> 28
-	res.Metadata.MinorVersion = (hdr.EngineVersion & 0xf000000) >> 24
-	res.Metadata.Revision = (hdr.EngineVersion & 0xff0000) >> 16
-	res.Metadata.BuildNumber = (hdr.EngineVersion & 0xffff)
-
-	// pretty.Log(res)
-	return res, nil
+func (t *lsfTables) scan(visit func(path []string, na NodeAttribute) error) error {
+	var path []string
+	return t.walk(func(i int, ni NodeInfo, name string) error {
+		if ni.FirstAttributeIndex == -1 {
+			return nil
+		}
 
+		// Nodes only store the index of their parent, the path is rebuilt from the node up to its region
+		path = path[:0]
+		for n := i; n != -1; n = t.nodeInfo[n].ParentIndex {
+			name, err := lookupName(t.names, t.nodeInfo[n].NameIndex, t.nodeInfo[n].NameOffset)
+			if err != nil {
+				return newLSFParseError(t.values, "values", err)
+			}
+			path = append(path, name)
+		}
+		for a, b := 0, len(path)-1; a < b; a, b = a+1, b-1 {
+			path[a], path[b] = path[b], path[a]
+		}
+		return nil
+	}, func(na NodeAttribute) error {
+		return visit(path, na)
+	})
 }
 
-var valueStart int64
+// walk calls visitNode for each node in the order of the node table, followed by visitAttribute for each of its attributes
+func (t *lsfTables) walk(visitNode func(i int, ni NodeInfo, name string) error, visitAttribute func(na NodeAttribute) error) error {
+	for i, ni := range t.nodeInfo {
+		if ni.ParentIndex < -1 || ni.ParentIndex >= i {
+			return newLSFParseError(t.values, "values", fmt.Errorf("parent index %d out of range", ni.ParentIndex))
+		}
+		nodeName, err := lookupName(t.names, ni.NameIndex, ni.NameOffset)
+		if err != nil {
+			return newLSFParseError(t.values, "values", err)
+		}
+		err = visitNode(i, ni, nodeName)
+		if err != nil {
+			return err
+		}
 
+		for count, index := 0, ni.FirstAttributeIndex; index != -1; count, index = count+1, t.attributeInfo[index].NextAttributeIndex {
+			if index < 0 || index >= len(t.attributeInfo) {
+				return newLSFParseError(t.values, "values", fmt.Errorf("node %s: attribute index %d out of range", nodeName, index))
+			}
+			if count >= len(t.attributeInfo) {
+				return newLSFParseError(t.values, "values", fmt.Errorf("node %s: attribute list loops at index %d", nodeName, index))
+			}
+			attribute := t.attributeInfo[index]
+			name, err := lookupName(t.names, attribute.NameIndex, attribute.NameOffset)
+			if err == nil {
+				_, err = t.values.Seek(int64(attribute.DataOffset), io.SeekStart)
+			}
+			if err != nil {
+				return newLSFParseError(t.values, "values", err)
+			}
+			na, err := ReadLSFAttribute(t.values, name, attribute.TypeId, attribute.Length, t.hdr.Version, t.hdr.EngineVersion)
+			if err != nil {
+				return newLSFParseError(t.values, "values", err)
+			}
+			err = visitAttribute(na)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadRegions reads the nodes described by nodeInfo and their attributes, r is the values section and the data
+// offsets of attributeInfo are relative to its start
 func ReadRegions(r io.ReadSeeker, names [][]string, nodeInfo []NodeInfo, attributeInfo []AttributeInfo, Version FileVersion, EngineVersion uint32) ([]*Node, error) {
 	NodeInstances := make([]*Node, 0, len(nodeInfo))
 	for _, nodeInfo := range nodeInfo {
@@ -949,6 +1164,9 @@ func ReadRegions(r io.ReadSeeker, names [][]string, nodeInfo []NodeInfo, attribu
 				return NodeInstances, err
 			}
 		} else {
+			if nodeInfo.ParentIndex < 0 || nodeInfo.ParentIndex >= len(NodeInstances) {
+				return NodeInstances, fmt.Errorf("parent index %d out of range", nodeInfo.ParentIndex)
+			}
 			node, err := ReadNode(r, nodeInfo, names, attributeInfo, Version, EngineVersion)
 
 			// pretty.Log(err, node)
@@ -965,6 +1183,14 @@ func ReadRegions(r io.ReadSeeker, names [][]string, nodeInfo []NodeInfo, attribu
 	return NodeInstances, nil
 }
 
+// lookupName returns the name at offset in the bucket index of the name hash table
+func lookupName(names [][]string, index, offset int) (string, error) {
+	if index < 0 || index >= len(names) || offset < 0 || offset >= len(names[index]) {
+		return "", fmt.Errorf("name %d:%d out of range", index, offset)
+	}
+	return names[index][offset], nil
+}
+
 func ReadNode(r io.ReadSeeker, ni NodeInfo, names [][]string, attributeInfo []AttributeInfo, Version FileVersion, EngineVersion uint32) (Node, error) {
 	var (
 		node  = Node{}
@@ -977,23 +1203,43 @@ func ReadNode(r io.ReadSeeker, ni NodeInfo, names [][]string, attributeInfo []At
 	l = log.With(Logger, "component", "LS converter", "file type", "lsf", "part", "node")
 	pos, err = r.Seek(0, io.SeekCurrent)
 
-	node.Name = names[ni.NameIndex][ni.NameOffset]
+	node.Name, err = lookupName(names, ni.NameIndex, ni.NameOffset)
+	if err != nil {
+		return node, err
+	}
 
 	l.Log("member", "name", "read", 0, "start position", pos, "value", node.Name)
 
+	// a FirstAttributeIndex or NextAttributeIndex of -1 marks the end of the attributes of the node, every other
+	// index must be in attributeInfo and a chain can not be longer than attributeInfo unless it loops
 	for index != -1 {
 		var (
-			attribute = attributeInfo[index]
+			attribute AttributeInfo
+			name      string
 			v         NodeAttribute
 		)
+		if index < 0 || index >= len(attributeInfo) {
+			return node, fmt.Errorf("node %s: attribute index %d out of range", node.Name, index)
+		}
+		if len(node.Attributes) >= len(attributeInfo) {
+			return node, fmt.Errorf("node %s: attribute list loops at index %d", node.Name, index)
+		}
+		attribute = attributeInfo[index]
+		name, err = lookupName(names, attribute.NameIndex, attribute.NameOffset)
+		if err != nil {
+			return node, err
+		}
 
-		if valueStart+int64(attribute.DataOffset) != pos {
-			pos, err = r.Seek(valueStart+int64(attribute.DataOffset), io.SeekStart)
-			if valueStart+int64(attribute.DataOffset) != pos || err != nil {
-				panic("shit")
+		if int64(attribute.DataOffset) != pos {
+			pos, err = r.Seek(int64(attribute.DataOffset), io.SeekStart)
+			if err != nil {
+				return node, err
+			}
+			if int64(attribute.DataOffset) != pos {
+				return node, fmt.Errorf("seeking to attribute value at %d ended at %d", attribute.DataOffset, pos)
 			}
 		}
-		v, err = ReadLSFAttribute(r, names[attribute.NameIndex][attribute.NameOffset], attribute.TypeId, attribute.Length, Version, EngineVersion)
+		v, err = ReadLSFAttribute(r, name, attribute.TypeId, attribute.Length, Version, EngineVersion)
 		node.Attributes = append(node.Attributes, v)
 		if err != nil {
 			return node, err
@@ -1099,7 +1345,7 @@ func ReadTranslatedString(r io.ReadSeeker, Version FileVersion, EngineVersion ui
 			return str, err
 		}
 		// logger.Printf("value length: %d value: %s read length: %d len of v: %d", vlength, v, n, len(v))
-		str.Value = string(v)
+		str.Value = string(v[:clen(v)])
 	}
 
 	var handleLength int32
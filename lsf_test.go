@@ -0,0 +1,435 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lsfFile returns res written as an uncompressed LSF file of version, so that reading it measures the reader alone
+func lsfFile(tb testing.TB, res *Resource, version FileVersion) []byte {
+	tb.Helper()
+	var buf bytes.Buffer
+	if err := (LSFWriter{Version: version, Compression: CMNone}).Write(&buf, res); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLSFReaderReadInto(t *testing.T) {
+	var (
+		lr  LSFReader
+		res = &Resource{}
+	)
+	// Read a large file first so that the second read reuses and must clear the larger Regions and buffer
+	for _, count := range []int{500, 20} {
+		want := namedResource(count)
+		want.Regions = append(want.Regions, NewRegion("Other"))
+		data := lsfFile(t, want, VerBG3)
+
+		read, err := ReadLSF(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := lr.ReadInto(bytes.NewBuffer(data), res); err != nil {
+			t.Fatal(err)
+		}
+		if !res.Equal(read) {
+			t.Errorf("%d nodes: ReadInto and ReadLSF differ", count)
+		}
+		if len(res.Regions) != 2 || len(res.Regions[0].Children) != count {
+			t.Errorf("%d nodes: got %d regions and %d children", count, len(res.Regions), len(res.Regions[0].Children))
+		}
+	}
+}
+
+func TestLSFReadConcurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		res     *Resource
+		version FileVersion
+	}{
+		{"small", namedResource(10), VerBG3},
+		{"large", namedResource(300), VerBG3},
+		{"nested", nestedResource(), VerBG3},
+		{"old layout", namedResource(50), VerInitial},
+	}
+	// Every file is read by ReadLSF and ReadInto in its own goroutine at the same time as the others, run with -race.
+	// The goroutines are started directly because parallel subtests run one at a time on a single CPU.
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(tests))
+	)
+	for i, tt := range tests {
+		data := lsfFile(t, tt.res, tt.version)
+		want, err := ReadLSF(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var lr LSFReader
+			for j := 0; j < 10; j++ {
+				got, err := ReadLSF(bytes.NewReader(data))
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				into := &Resource{}
+				if err := lr.ReadInto(bytes.NewBuffer(data), into); err != nil {
+					errs[i] = err
+					return
+				}
+				if !got.Equal(want) || !into.Equal(want) {
+					errs[i] = fmt.Errorf("read %d differs from the first read", j)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs[i] != nil {
+				t.Error(errs[i])
+			}
+		})
+	}
+}
+
+func BenchmarkLSFRead(b *testing.B) {
+	data := lsfFile(b, namedResource(2000), VerBG3)
+	b.Run("ReadLSF", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ReadLSF(bytes.NewBuffer(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ReadInto", func(b *testing.B) {
+		var (
+			lr  LSFReader
+			res = &Resource{}
+		)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := lr.ReadInto(bytes.NewBuffer(data), res); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ScanLSF", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			err := ScanLSF(bytes.NewBuffer(data), func(path []string, na NodeAttribute) error {
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestScanLSF(t *testing.T) {
+	data := lsfFile(t, namedResource(20), VerBG3)
+	errVisit := errors.New("visit failed")
+	tests := []struct {
+		name       string
+		stopAt     string
+		stopErr    error
+		wantVisits int
+		wantErr    error
+	}{
+		{"all", "", nil, 20, nil},
+		{"stop at first", "Attribute0", ErrStopScan, 1, nil},
+		{"stop at tenth", "Attribute9", ErrStopScan, 10, nil},
+		{"wrapped stop", "Attribute4", fmt.Errorf("found: %w", ErrStopScan), 5, nil},
+		{"error", "Attribute2", errVisit, 3, errVisit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visits := 0
+			err := ScanLSF(bytes.NewReader(data), func(path []string, na NodeAttribute) error {
+				visits++
+				if want := fmt.Sprintf("Region/Node%d", na.Value); strings.Join(path, "/") != want {
+					t.Errorf("got path %v, want %s", path, want)
+				}
+				if na.Name == tt.stopAt {
+					return tt.stopErr
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if visits != tt.wantVisits {
+				t.Errorf("got %d visits, want %d", visits, tt.wantVisits)
+			}
+		})
+	}
+}
+
+func TestLSFValuesSection(t *testing.T) {
+	root := NewRegion("Region")
+	root.Attributes = []NodeAttribute{
+		{Name: "Flag", Type: DT_Bool, Value: true},
+		{Name: "Small", Type: DT_Byte, Value: uint8(7)},
+		{Name: "Int", Type: DT_Int, Value: int32(-5)},
+		{Name: "Long", Type: DT_LSString, Value: strings.Repeat("long value ", 200)},
+		{Name: "Buffer", Type: DT_ScratchBuffer, Value: bytes.Repeat([]byte{1, 2, 3}, 1000)},
+		{Name: "Short", Type: DT_FixedString, Value: "x"},
+	}
+	res := &Resource{Regions: []*Node{root}}
+	tests := []struct {
+		name    string
+		version FileVersion
+	}{
+		{"initial", VerInitial},
+		{"extended nodes", VerExtendedNodes},
+		{"BG3", VerBG3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := lsfFile(t, res, tt.version)
+			tables, err := readLSFTables(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Every value, however small, follows the previous one in the values section
+			var offset uint
+			for i, attr := range tables.attributeInfo {
+				if attr.DataOffset != offset {
+					t.Errorf("attribute %d: got offset %d, want %d", i, attr.DataOffset, offset)
+				}
+				offset += attr.Length
+			}
+			if size, _ := tables.values.Seek(0, io.SeekEnd); uint(size) != offset {
+				t.Errorf("values section has %d bytes, attributes hold %d", size, offset)
+			}
+
+			got, err := ReadLSF(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range root.Attributes {
+				if attr, ok := got.Regions[0].Attribute(want.Name); !ok || !attr.Equal(want) {
+					t.Errorf("got %#v, want %#v", attr, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLSFNodeLayouts(t *testing.T) {
+	want := nestedResource()
+	nodes := 0
+	for _, region := range want.Regions {
+		region.walk(func(*Node) bool {
+			nodes++
+			return true
+		})
+	}
+	tests := []struct {
+		name      string
+		version   FileVersion
+		entrySize int
+	}{
+		{"short entries", VerChunkedCompress, 12},
+		{"long entries", VerExtendedNodes, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := lsfFile(t, want, tt.version)
+			tables, err := readLSFTables(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tables.nodeInfo) != nodes || int(tables.hdr.NodesUncompressedSize) != nodes*tt.entrySize {
+				t.Errorf("got %d nodes in %d bytes, want %d nodes of %d bytes", len(tables.nodeInfo), tables.hdr.NodesUncompressedSize, nodes, tt.entrySize)
+			}
+			got, err := ReadLSF(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Error("the nodes read differ from the nodes written")
+			}
+		})
+	}
+}
+
+func TestLSFNodesWithoutAttributes(t *testing.T) {
+	root := NewRegion("Region")
+	empty := &Node{Name: "Empty", Parent: root}
+	full := &Node{Name: "Full", Parent: root, Attributes: []NodeAttribute{{Name: "A", Type: DT_Int, Value: int32(1)}}}
+	leaf := &Node{Name: "Leaf", Parent: empty}
+	empty.Children = []*Node{leaf}
+	root.Children = []*Node{empty, full}
+	want := &Resource{Regions: []*Node{root}}
+
+	for _, version := range []FileVersion{VerInitial, VerChunkedCompress, VerExtendedNodes, VerBG3} {
+		t.Run(version.String(), func(t *testing.T) {
+			data := lsfFile(t, want, version)
+			tables, err := readLSFTables(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, ni := range tables.nodeInfo {
+				if i != 3 && ni.FirstAttributeIndex != -1 {
+					t.Errorf("node %d: got first attribute %d, want -1", i, ni.FirstAttributeIndex)
+				}
+			}
+
+			got, err := ReadLSF(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			region := got.Regions[0]
+			if len(region.Attributes) != 0 || len(region.Children[0].Attributes) != 0 || len(region.Children[0].Children[0].Attributes) != 0 {
+				t.Errorf("nodes without attributes were read with attributes: %v", region)
+			}
+			if !got.Equal(want) {
+				t.Error("the nodes read differ from the nodes written")
+			}
+		})
+	}
+}
+
+func TestLSFHeaderSizes(t *testing.T) {
+	max := LSFHeader{
+		Signature:                  LSFSignature,
+		Version:                    VerBG3,
+		StringsUncompressedSize:    math.MaxUint32,
+		StringsSizeOnDisk:          math.MaxUint32 - 1,
+		NodesUncompressedSize:      math.MaxUint32 - 2,
+		NodesSizeOnDisk:            math.MaxUint32 - 3,
+		AttributesUncompressedSize: math.MaxUint32 - 4,
+		AttributesSizeOnDisk:       math.MaxUint32 - 5,
+		ValuesUncompressedSize:     math.MaxUint32 - 6,
+		ValuesSizeOnDisk:           math.MaxUint32 - 7,
+		CompressionFlags:           byte(MakeCompressionFlags(CMZlib, DefaultCompression)),
+	}
+	var buf bytes.Buffer
+	if err := max.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// Every section size is 32 bits wide in every version
+	if buf.Len() != 4+4+4+8*4+1+1+2+4 {
+		t.Errorf("header has %d bytes", buf.Len())
+	}
+	got, err := ReadLSFHeader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != max {
+		t.Errorf("got %+v, want %+v", *got, max)
+	}
+
+	// A section that declares more uncompressed data than it holds is an error, not a short read
+	var data bytes.Buffer
+	if err := (LSFWriter{Version: VerBG3, Compression: CMZlib}).Write(&data, namedResource(10)); err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := ReadLSFHeader(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr.ValuesUncompressedSize += 1 << 20
+	var patched bytes.Buffer
+	if err := hdr.Write(&patched); err != nil {
+		t.Fatal(err)
+	}
+	patched.Write(data.Bytes()[binary.Size(hdr):])
+	var pe LSFParseError
+	if _, err := ReadLSF(&patched); !errors.As(err, &pe) || pe.Phase != "values" {
+		t.Errorf("got error %v, want an LSFParseError in the values section", err)
+	}
+}
+
+func TestLSFTypeIDs(t *testing.T) {
+	root := NewRegion("Region")
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		if value, ok := sampleValues[dt]; ok {
+			root.Attributes = append(root.Attributes, NodeAttribute{Name: dt.String(), Type: dt, Value: value})
+		}
+	}
+	res := &Resource{Regions: []*Node{root}}
+	// The type IDs of LSF files are the same in every version
+	for _, version := range []FileVersion{VerInitial, VerChunkedCompress, VerExtendedNodes, VerBG3} {
+		t.Run(version.String(), func(t *testing.T) {
+			tables, err := readLSFTables(bytes.NewReader(lsfFile(t, res, version)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tables.attributeInfo) != len(root.Attributes) {
+				t.Fatalf("got %d attributes, want %d", len(tables.attributeInfo), len(root.Attributes))
+			}
+			for i, attr := range tables.attributeInfo {
+				id, err := root.Attributes[i].Type.BinaryID()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if uint8(attr.TypeId) != id {
+					t.Errorf("%v: got type ID %d, want %d", root.Attributes[i].Type, attr.TypeId, id)
+				}
+			}
+		})
+	}
+}
+
+func TestLSFExtendedFlag(t *testing.T) {
+	want := nestedResource()
+	tests := []struct {
+		version  FileVersion
+		extended uint32
+	}{
+		{VerInitial, 0},
+		{VerChunkedCompress, 0},
+		{VerExtendedNodes, 1},
+		{VerBG3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version.String(), func(t *testing.T) {
+			data := lsfFile(t, want, tt.version)
+			hdr, err := ReadLSFHeader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Extended != tt.extended {
+				t.Errorf("got extended flag %d, want %d", hdr.Extended, tt.extended)
+			}
+
+			// The flag selects the layout of the node and attribute tables, a file read with the other
+			// layout does not read as the file written
+			hdr.Extended ^= 1
+			var patched bytes.Buffer
+			if err := hdr.Write(&patched); err != nil {
+				t.Fatal(err)
+			}
+			patched.Write(data[binary.Size(hdr):])
+			flagged := patched.Bytes()
+			if again, err := ReadLSFHeader(bytes.NewReader(flagged)); err != nil || again.Extended != hdr.Extended {
+				t.Fatalf("got header %+v, %v, want extended flag %d", again, err, hdr.Extended)
+			}
+			got, err := ReadLSF(bytes.NewReader(flagged))
+			switch {
+			case tt.version < VerExtendedNodes:
+				// Older versions always use the short layout
+				if err != nil || !got.Equal(want) {
+					t.Errorf("the flag changed the layout of version %v: %v", tt.version, err)
+				}
+			case err == nil && got.Equal(want):
+				t.Error("clearing the flag did not change the layout")
+			}
+		})
+	}
+}
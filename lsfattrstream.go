@@ -0,0 +1,91 @@
+package lslib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+)
+
+// TypedAttributeStream decodes the attributes of an LSF file one at a time, in the order they are stored in the
+// attribute table, without building nodes. The sections of the file are decompressed when the stream is created,
+// each attribute value is only decoded when it is returned.
+//
+// Next must not be used while the channel of All or AllContext is open, a TypedAttributeStream is not safe for
+// concurrent use.
+type TypedAttributeStream struct {
+	tables *lsfTables
+	next   int
+	err    error
+}
+
+// NewTypedAttributeStream reads the header and tables of the LSF file in r, r is read into memory first if it is
+// not an io.ReadSeeker
+func NewTypedAttributeStream(r io.Reader) (*TypedAttributeStream, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		rs = bytes.NewReader(data)
+	}
+	tables, err := readLSFTables(rs)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedAttributeStream{tables: tables}, nil
+}
+
+// Next decodes the next attribute, it returns false once every attribute has been returned or decoding failed
+func (s *TypedAttributeStream) Next() (NodeAttribute, bool) {
+	if s.err != nil || s.next >= len(s.tables.attributeInfo) {
+		return NodeAttribute{}, false
+	}
+	attribute := s.tables.attributeInfo[s.next]
+	s.next++
+
+	name, err := lookupName(s.tables.names, attribute.NameIndex, attribute.NameOffset)
+	if err == nil {
+		_, err = s.tables.values.Seek(int64(attribute.DataOffset), io.SeekStart)
+	}
+	if err != nil {
+		s.err = newLSFParseError(s.tables.values, "values", err)
+		return NodeAttribute{}, false
+	}
+	na, err := ReadLSFAttribute(s.tables.values, name, attribute.TypeId, attribute.Length, s.tables.hdr.Version, s.tables.hdr.EngineVersion)
+	if err != nil {
+		s.err = newLSFParseError(s.tables.values, "values", err)
+		return NodeAttribute{}, false
+	}
+	return na, true
+}
+
+// All returns a channel yielding the remaining attributes, it is closed after the last one or when decoding fails.
+// The attributes are decoded by a separate goroutine which only exits once every attribute has been received,
+// use AllContext to stop early.
+func (s *TypedAttributeStream) All() <-chan NodeAttribute {
+	return s.AllContext(context.Background())
+}
+
+// AllContext is All, the channel is also closed when ctx is done. The attribute that was decoded when ctx is done
+// is not sent and is skipped by the stream.
+func (s *TypedAttributeStream) AllContext(ctx context.Context) <-chan NodeAttribute {
+	attrs := make(chan NodeAttribute)
+	go func() {
+		defer close(attrs)
+		for na, ok := s.Next(); ok; na, ok = s.Next() {
+			select {
+			case attrs <- na:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return attrs
+}
+
+// Err returns the error that stopped the stream, it is nil if every attribute was decoded
+func (s *TypedAttributeStream) Err() error {
+	return s.err
+}
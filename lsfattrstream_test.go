@@ -0,0 +1,91 @@
+package lslib
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTypedAttributeStream(t *testing.T) {
+	data := lsfFile(t, namedResource(10), VerBG3)
+	tests := []struct {
+		name string
+		read func(s *TypedAttributeStream) []NodeAttribute
+	}{
+		{"Next", func(s *TypedAttributeStream) []NodeAttribute {
+			var attrs []NodeAttribute
+			for na, ok := s.Next(); ok; na, ok = s.Next() {
+				attrs = append(attrs, na)
+			}
+			return attrs
+		}},
+		{"All", func(s *TypedAttributeStream) []NodeAttribute {
+			var attrs []NodeAttribute
+			for na := range s.All() {
+				attrs = append(attrs, na)
+			}
+			return attrs
+		}},
+		{"AllContext", func(s *TypedAttributeStream) []NodeAttribute {
+			var attrs []NodeAttribute
+			for na := range s.AllContext(context.Background()) {
+				attrs = append(attrs, na)
+			}
+			return attrs
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewTypedAttributeStream(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			attrs := tt.read(s)
+			if s.Err() != nil {
+				t.Fatal(s.Err())
+			}
+			if len(attrs) != 10 {
+				t.Fatalf("got %d attributes, want 10", len(attrs))
+			}
+			for i, na := range attrs {
+				want := namedResource(10).Regions[0].Children[i].Attributes[0]
+				if !na.Equal(want) {
+					t.Errorf("got %#v, want %#v", na, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTypedAttributeStreamCancel(t *testing.T) {
+	s, err := NewTypedAttributeStream(bytes.NewReader(lsfFile(t, namedResource(100), VerBG3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	attrs := s.AllContext(ctx)
+	if na := <-attrs; na.Name != "Attribute0" {
+		t.Errorf("got %s, want Attribute0", na.Name)
+	}
+	cancel()
+
+	// The goroutine stops before sending the remaining 99 attributes. Sending and stopping may both be ready
+	// while the channel is received from, so a few more attributes can arrive.
+	timeout := time.After(time.Second)
+	received := 0
+	for {
+		select {
+		case _, ok := <-attrs:
+			if !ok {
+				if received >= 99 {
+					t.Errorf("received every attribute after cancelling")
+				}
+				return
+			}
+			received++
+		case <-timeout:
+			t.Fatal("the channel was not closed after cancelling")
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package lslib
+
+import "io"
+
+// Number of nodes WriteNode can queue before it blocks. This bounds the nodes waiting to be encoded,
+// not the memory of the writer, which holds every encoded section until Close.
+const lsfStreamQueueSize = 16
+
+// LSFOption configures an LSFStreamWriter
+type LSFOption func(*lsfStreamOptions)
+
+type lsfStreamOptions struct {
+	LSFWriter
+	Metadata LSMetadata
+}
+
+// WithLSFVersion sets the version of the written file, the default is MaxVersion
+func WithLSFVersion(version FileVersion) LSFOption {
+	return func(o *lsfStreamOptions) {
+		o.Version = version
+	}
+}
+
+// WithLSFCompression sets the compression method and level of each section, the default is no compression
+func WithLSFCompression(method CompressionMethod, level CompressionLevel) LSFOption {
+	return func(o *lsfStreamOptions) {
+		o.Compression = method
+		o.Level = level
+	}
+}
+
+// WithLSFMetadata sets the engine version written in the header
+func WithLSFMetadata(metadata LSMetadata) LSFOption {
+	return func(o *lsfStreamOptions) {
+		o.Metadata = metadata
+	}
+}
+
+// WithLSFRejectNilUUID makes WriteNode fail when a DT_UUID attribute holds uuid.Nil
+// unless the attribute is named in intentional
+func WithLSFRejectNilUUID(intentional map[string]bool) LSFOption {
+	return func(o *lsfStreamOptions) {
+		o.RejectNilUUID = true
+		o.IntentionalNilUUID = intentional
+	}
+}
+
+type lsfStreamItem struct {
+	node *Node
+	done chan error
+}
+
+// LSFStreamWriter encodes regions of an LSF file as they are produced and writes the file on Close.
+//
+// Nodes passed to WriteNode are encoded by a separate goroutine while the caller builds the next one,
+// WriteNode blocks once the queue of nodes waiting to be encoded is full. It does not write to a slow sink
+// as the nodes are produced: the header of an LSF file holds the size of every section, so nothing is written
+// to the underlying writer until Close, which compresses the sections concurrently and writes the file.
+//
+// The encoded sections are kept in memory until Close, so the memory used grows with the size of the file.
+// Seeking in the underlying writer would not help: the name table is the first section of the file and is
+// only complete once the last node has been encoded.
+//
+// An LSFStreamWriter is not safe for concurrent use.
+type LSFStreamWriter struct {
+	w       io.Writer
+	opts    lsfStreamOptions
+	enc     *lsfEncoder
+	items   chan lsfStreamItem
+	encoded chan error
+	closed  bool
+	// err is returned by every method if the options are invalid
+	err error
+}
+
+// NewLSFStreamWriter returns a writer that writes an LSF file to w. If the version set by the options is not
+// supported, every method of the writer returns an error wrapping ErrUnsupportedVersion.
+func NewLSFStreamWriter(w io.Writer, opts ...LSFOption) *LSFStreamWriter {
+	sw := &LSFStreamWriter{
+		w:       w,
+		items:   make(chan lsfStreamItem, lsfStreamQueueSize),
+		encoded: make(chan error, 1),
+	}
+	sw.opts.Version = MaxVersion
+	for _, opt := range opts {
+		opt(&sw.opts)
+	}
+	if _, sw.err = ParseFileVersion(uint32(sw.opts.Version)); sw.err != nil {
+		return sw
+	}
+	sw.enc = newLSFEncoder(sw.opts.Version, sw.opts.Metadata)
+	go sw.encode()
+	return sw
+}
+
+// encode encodes queued nodes until the queue is closed, nodes queued after an error are discarded
+func (sw *LSFStreamWriter) encode() {
+	var err error
+	for item := range sw.items {
+		if item.node != nil && err == nil {
+			if sw.opts.RejectNilUUID {
+				err = checkNilUUID(&Resource{Regions: []*Node{item.node}}, sw.opts.IntentionalNilUUID)
+			}
+			if err == nil {
+				err = sw.enc.encodeNode(item.node, -1, -1)
+			}
+		}
+		if item.done != nil {
+			item.done <- err
+		}
+	}
+	sw.encoded <- err
+}
+
+// WriteNode queues n and its children to be encoded as a region, nothing is written to the underlying writer
+// before Close. n must not be modified until Wait or Close returns.
+func (sw *LSFStreamWriter) WriteNode(n *Node) error {
+	if sw.closed {
+		return ErrWriterClosed
+	}
+	if sw.err != nil {
+		return sw.err
+	}
+	sw.items <- lsfStreamItem{node: n}
+	return nil
+}
+
+// Wait waits until every queued node has been encoded and returns the first encoding error.
+// It does not write to the underlying writer.
+func (sw *LSFStreamWriter) Wait() error {
+	if sw.closed {
+		return ErrWriterClosed
+	}
+	if sw.err != nil {
+		return sw.err
+	}
+	done := make(chan error, 1)
+	sw.items <- lsfStreamItem{done: done}
+	return <-done
+}
+
+// Close encodes the remaining nodes and writes the file, it does not close the underlying writer
+func (sw *LSFStreamWriter) Close() error {
+	if sw.closed {
+		return ErrWriterClosed
+	}
+	sw.closed = true
+	if sw.err != nil {
+		return sw.err
+	}
+	close(sw.items)
+	err := <-sw.encoded
+	if err != nil {
+		return err
+	}
+	return sw.enc.writeTo(sw.w, sw.opts.Compression, sw.opts.Level)
+}
@@ -0,0 +1,72 @@
+package lslib
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLSFStreamWriter(t *testing.T) {
+	res := namedResource(100)
+	res.Regions = append(res.Regions, NewRegion("Other"))
+
+	var want bytes.Buffer
+	if err := (LSFWriter{Version: VerBG3, Compression: CMZlib, Level: DefaultCompression}).Write(&want, res); err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	sw := NewLSFStreamWriter(&got, WithLSFVersion(VerBG3), WithLSFCompression(CMZlib, DefaultCompression))
+	for _, region := range res.Regions {
+		if err := sw.WriteNode(region); err != nil {
+			t.Fatal(err)
+		}
+		if err := sw.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if got.Len() != 0 {
+			t.Fatalf("%d bytes were written before Close", got.Len())
+		}
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("the streamed file differs from the one written by LSFWriter")
+	}
+	if err := sw.WriteNode(NewRegion("Late")); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("WriteNode after Close: got %v, want ErrWriterClosed", err)
+	}
+}
+
+func TestLSFStreamWriterVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version FileVersion
+		wantErr error
+	}{
+		{"initial", VerInitial, nil},
+		{"latest", MaxVersion, nil},
+		{"zero", 0, ErrUnsupportedVersion},
+		{"newer", FileVersion(MaxVersion + 1), ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			sw := NewLSFStreamWriter(&buf, WithLSFVersion(tt.version))
+			// The version must be rejected before any node is encoded
+			if err := sw.WriteNode(namedResource(1).Regions[0]); !errors.Is(err, tt.wantErr) {
+				t.Errorf("WriteNode: got %v, want %v", err, tt.wantErr)
+			}
+			if err := sw.Wait(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Wait: got %v, want %v", err, tt.wantErr)
+			}
+			if err := sw.Close(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Close: got %v, want %v", err, tt.wantErr)
+			}
+			if wrote := buf.Len() > 0; wrote != (tt.wantErr == nil) {
+				t.Errorf("wrote %d bytes", buf.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,509 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/pierrec/lz4/v4"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Number of buckets in the name hash table
+const lsfNameHashSize = 0x200
+
+// LSFWriter writes a Resource as an LSF file
+type LSFWriter struct {
+	Version     FileVersion
+	Compression CompressionMethod
+	Level       CompressionLevel
+
+	// RejectNilUUID makes Write fail when a DT_UUID attribute holds uuid.Nil,
+	// an unset UUID is almost always an authoring mistake
+	RejectNilUUID bool
+
+	// IntentionalNilUUID lists the names of attributes that may hold uuid.Nil when RejectNilUUID is set
+	IntentionalNilUUID map[string]bool
+}
+
+// WriteLSF writes res to w as an LSF file of the given version, each section is compressed using method and level
+func WriteLSF(w io.Writer, res *Resource, version FileVersion, method CompressionMethod, level CompressionLevel) error {
+	return LSFWriter{
+		Version:     version,
+		Compression: method,
+		Level:       level,
+	}.Write(w, res)
+}
+
+// Write writes res to w as an LSF file
+func (lw LSFWriter) Write(w io.Writer, res *Resource) error {
+	var err error
+	if _, err := ParseFileVersion(uint32(lw.Version)); err != nil {
+		return err
+	}
+	if lw.RejectNilUUID {
+		err = checkNilUUID(res, lw.IntentionalNilUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	enc := newLSFEncoder(lw.Version, res.Metadata)
+	for _, region := range res.Regions {
+		err = enc.encodeNode(region, -1, -1)
+		if err != nil {
+			return err
+		}
+	}
+	return enc.writeTo(w, lw.Compression, lw.Level)
+}
+
+// lsfEncoder builds the sections of an LSF file
+type lsfEncoder struct {
+	version       FileVersion
+	engineVersion uint32
+
+	names     [][]string
+	nameIndex map[string]uint32
+
+	nodes      bytes.Buffer
+	attributes bytes.Buffer
+	values     bytes.Buffer
+
+	nodeCount      int32
+	attributeCount int32
+}
+
+func newLSFEncoder(version FileVersion, metadata LSMetadata) *lsfEncoder {
+	return &lsfEncoder{
+		version:       version,
+		engineVersion: metadata.MajorVersion<<28 | metadata.MinorVersion<<24 | metadata.Revision<<16 | metadata.BuildNumber,
+		names:         make([][]string, lsfNameHashSize),
+		nameIndex:     make(map[string]uint32),
+	}
+}
+
+// extended reports whether the long node and attribute entries are used
+func (enc *lsfEncoder) extended() bool {
+	return enc.version >= VerExtendedNodes
+}
+
+// addName returns the name hash table index of name (16-bit MSB: bucket, 16-bit LSB: offset in bucket),
+// adding it to the table if needed. Names are stored with a 16-bit length, longer names return ErrNameTooLong.
+// A bucket holds at most math.MaxUint16 names, adding a name to a full bucket returns ErrNameTableFull.
+func (enc *lsfEncoder) addName(name string) (uint32, error) {
+	if index, ok := enc.nameIndex[name]; ok {
+		return index, nil
+	}
+	if len(name) > math.MaxUint16 {
+		return 0, fmt.Errorf("%d bytes: %w", len(name), ErrNameTooLong)
+	}
+	// The number of names in a bucket is stored in 16 bits
+	bucket := lsfNameBucket(name)
+	if len(enc.names[bucket]) >= math.MaxUint16 {
+		return 0, fmt.Errorf("bucket %d already holds %d names: %w", bucket, len(enc.names[bucket]), ErrNameTableFull)
+	}
+
+	index := bucket<<16 | uint32(len(enc.names[bucket]))
+	enc.names[bucket] = append(enc.names[bucket], name)
+	enc.nameIndex[name] = index
+	return index, nil
+}
+
+// lsfNameBucket returns the bucket of the name hash table holding name
+func lsfNameBucket(name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	hash := h.Sum32()
+	return (hash & 0x1ff) ^ ((hash >> 9) & 0x1ff) ^ ((hash >> 18) & 0x1ff) ^ ((hash >> 27) & 0x1ff)
+}
+
+// encodeNode adds n and all of its children, nodes are written depth first so that parents always precede their children
+func (enc *lsfEncoder) encodeNode(n *Node, parent, nextSibling int32) error {
+	var (
+		index          = enc.nodeCount
+		firstAttribute = int32(-1)
+		entry          []interface{}
+	)
+	enc.nodeCount++
+
+	if len(n.Attributes) > 0 {
+		firstAttribute = enc.attributeCount
+	}
+	name, err := enc.addName(n.Name)
+	if err != nil {
+		return fmt.Errorf("node %.64s: %w", n.Name, err)
+	}
+	if enc.extended() {
+		entry = []interface{}{name, parent, nextSibling, firstAttribute}
+	} else {
+		entry = []interface{}{name, firstAttribute, parent}
+	}
+	for _, v := range entry {
+		binary.Write(&enc.nodes, binary.LittleEndian, v)
+	}
+
+	for i, attr := range n.Attributes {
+		next := int32(-1)
+		if i < len(n.Attributes)-1 {
+			next = enc.attributeCount + 1
+		}
+		err = enc.encodeAttribute(attr, index, next)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", n.Name, err)
+		}
+	}
+
+	for i, child := range n.Children {
+		next := int32(-1)
+		if i < len(n.Children)-1 {
+			next = enc.nodeCount + int32(child.RecursiveNodeCount())
+		}
+		err = enc.encodeNode(child, index, next)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (enc *lsfEncoder) encodeAttribute(attr NodeAttribute, node, next int32) error {
+	var (
+		offset = enc.values.Len()
+		entry  []interface{}
+	)
+	name, err := enc.addName(attr.Name)
+	if err != nil {
+		return fmt.Errorf("attribute %.64s: %w", attr.Name, err)
+	}
+	typeID, err := attr.Type.BinaryID()
+	if err != nil {
+		return fmt.Errorf("attribute %s: %w", attr.Name, err)
+	}
+	err = writeLSFAttribute(&enc.values, attr, enc.version, enc.engineVersion)
+	if err != nil {
+		return fmt.Errorf("attribute %s: %w", attr.Name, err)
+	}
+	length := enc.values.Len() - offset
+	if length >= 1<<26 {
+		return fmt.Errorf("attribute %s: value of %d bytes is too long", attr.Name, length)
+	}
+	enc.attributeCount++
+
+	typeAndLength := uint32(typeID)&0x3f | uint32(length)<<6
+	if enc.extended() {
+		entry = []interface{}{name, typeAndLength, next, uint32(offset)}
+	} else {
+		entry = []interface{}{name, typeAndLength, node}
+	}
+	for _, v := range entry {
+		binary.Write(&enc.attributes, binary.LittleEndian, v)
+	}
+	return nil
+}
+
+func (enc *lsfEncoder) encodeNames() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(len(enc.names)))
+	for _, bucket := range enc.names {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(bucket)))
+		for _, name := range bucket {
+			binary.Write(&buf, binary.LittleEndian, uint16(len(name)))
+			buf.WriteString(name)
+		}
+	}
+	return buf.Bytes()
+}
+
+// writeTo writes the header followed by each section compressed with method and level
+func (enc *lsfEncoder) writeTo(w io.Writer, method CompressionMethod, level CompressionLevel) error {
+	var (
+		hdr = LSFHeader{
+			Signature:        LSFSignature,
+			Version:          enc.version,
+			EngineVersion:    enc.engineVersion,
+			CompressionFlags: byte(MakeCompressionFlags(method, level)),
+		}
+		chunked  = enc.version >= VerChunkedCompress
+		sections = []struct {
+			data               []byte
+			chunked            bool
+			uncompressed, disk *uint32
+		}{
+			{enc.encodeNames(), false, &hdr.StringsUncompressedSize, &hdr.StringsSizeOnDisk},
+			{enc.nodes.Bytes(), chunked, &hdr.NodesUncompressedSize, &hdr.NodesSizeOnDisk},
+			{enc.attributes.Bytes(), chunked, &hdr.AttributesUncompressedSize, &hdr.AttributesSizeOnDisk},
+			{enc.values.Bytes(), chunked, &hdr.ValuesUncompressedSize, &hdr.ValuesSizeOnDisk},
+		}
+		compressed = make([][]byte, len(sections))
+		err        error
+	)
+	if enc.extended() {
+		hdr.Extended = 1
+	}
+
+	// Sections are compressed concurrently, the header can only be written once all of them are done
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(sections))
+	)
+	for i, section := range sections {
+		*section.uncompressed = uint32(len(section.data))
+		if len(section.data) == 0 {
+			// Every version reads an empty section from its sizes alone, an empty compressed stream would
+			// still take up the zlib or LZ4 frame header so the section is left out entirely
+			continue
+		}
+		wg.Add(1)
+		go func(i int, data []byte, chunked bool) {
+			defer wg.Done()
+			compressed[i], errs[i] = compressSection(data, method, level, chunked)
+		}(i, section.data, section.chunked)
+	}
+	wg.Wait()
+	for i, section := range sections {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		if hdr.IsCompressed() {
+			*section.disk = uint32(len(compressed[i]))
+		}
+	}
+
+	err = hdr.Write(w)
+	if err != nil {
+		return err
+	}
+	for _, data := range compressed {
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes the header in the layout read by LSFHeader.Read
+func (lsfh LSFHeader) Write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, lsfh)
+}
+
+// compressSection compresses data using method and level, chunked sections
+// are written as an LZ4 frame instead of a single LZ4 block
+func compressSection(data []byte, method CompressionMethod, level CompressionLevel, chunked bool) ([]byte, error) {
+	if method != CMLZ4 || !chunked {
+		return Compress(data, method, level)
+	}
+	var (
+		buf bytes.Buffer
+		err error
+		zw  = lz4.NewWriter(&buf)
+	)
+	if level == MaxCompression {
+		err = zw.Apply(lz4.CompressionLevelOption(lz4.Level9))
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = zw.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	return buf.Bytes(), err
+}
+
+// writeLSFAttribute writes the value of attr as it is stored in the value section of an LSF file
+func writeLSFAttribute(w io.Writer, attr NodeAttribute, version FileVersion, engineVersion uint32) error {
+	switch attr.Type {
+	case DT_String, DT_Path, DT_FixedString, DT_LSString, DT_WString, DT_LSWString:
+		v, ok := attr.Value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string for %v, got %T", attr.Type, attr.Value)
+		}
+		_, err := io.WriteString(w, v+"\x00")
+		return err
+
+	case DT_TranslatedString:
+		v, ok := attr.Value.(TranslatedString)
+		if !ok {
+			return fmt.Errorf("expected a TranslatedString for %v, got %T", attr.Type, attr.Value)
+		}
+		return writeTranslatedString(w, v, version, engineVersion)
+
+	case DT_TranslatedFSString:
+		v, ok := attr.Value.(TranslatedFSString)
+		if !ok {
+			return fmt.Errorf("expected a TranslatedFSString for %v, got %T", attr.Type, attr.Value)
+		}
+		return writeTranslatedFSString(w, v, version)
+
+	case DT_ScratchBuffer:
+		v, ok := attr.Value.([]byte)
+		if !ok {
+			return fmt.Errorf("expected a []byte for %v, got %T", attr.Type, attr.Value)
+		}
+		_, err := w.Write(v)
+		return err
+
+	default:
+		return writeAttribute(w, attr)
+	}
+}
+
+// writeAttribute writes the value of the types that are serialized the same way by every binary format, it is the inverse of ReadAttribute
+func writeAttribute(w io.Writer, attr NodeAttribute) error {
+	if goType := attr.Type.goType(); goType != nil && reflect.TypeOf(attr.Value) != goType {
+		return fmt.Errorf("value %v of type %T can not be written as %v", attr.Value, attr.Value, attr.Type)
+	}
+	var v interface{}
+	switch attr.Type {
+	case DT_None:
+		return nil
+
+	case DT_Byte:
+		v, _ = attr.Value.(uint8)
+	case DT_Short:
+		v, _ = attr.Value.(int16)
+	case DT_UShort:
+		v, _ = attr.Value.(uint16)
+	case DT_Int:
+		v, _ = attr.Value.(int32)
+	case DT_UInt:
+		v, _ = attr.Value.(uint32)
+	case DT_Float:
+		v, _ = attr.Value.(float32)
+	case DT_Double:
+		v, _ = attr.Value.(float64)
+	case DT_Bool:
+		v, _ = attr.Value.(bool)
+	case DT_ULongLong:
+		v, _ = attr.Value.(uint64)
+	case DT_Long, DT_Int64:
+		v, _ = attr.Value.(int64)
+	case DT_Int8:
+		v, _ = attr.Value.(int8)
+
+	case DT_IVec2, DT_IVec3, DT_IVec4:
+		vec, ok := attr.Value.(Ivec)
+		col, _ := attr.GetColumns()
+		if ok && len(vec) == col {
+			ints := make([]int32, col)
+			for i := range vec {
+				ints[i] = int32(vec[i])
+			}
+			v = ints
+		}
+
+	case DT_Vec2, DT_Vec3, DT_Vec4:
+		vec, ok := attr.Value.(Vec)
+		col, _ := attr.GetColumns()
+		if ok && len(vec) == col {
+			floats := make([]float32, col)
+			for i := range vec {
+				floats[i] = float32(vec[i])
+			}
+			v = floats
+		}
+
+	case DT_Mat2, DT_Mat3, DT_Mat3x4, DT_Mat4x3, DT_Mat4:
+		m, ok := attr.Value.(*Mat)
+		col, _ := attr.GetColumns()
+		row, _ := attr.GetRows()
+		if ok && m != nil {
+			M := (*mat.Dense)(m)
+			if r, c := M.Dims(); r == row && c == col {
+				// Matrices are stored column by column
+				floats := make([]float32, 0, col*row)
+				for c := 0; c < col; c++ {
+					for r := 0; r < row; r++ {
+						floats = append(floats, float32(M.At(r, c)))
+					}
+				}
+				v = floats
+			}
+		}
+
+	case DT_UUID:
+		id, ok := attr.Value.(uuid.UUID)
+		if ok {
+			p := id
+			reverse(p[:4])
+			reverse(p[4:6])
+			reverse(p[6:8])
+			v = p
+		}
+
+	default:
+		return fmt.Errorf("writeAttribute() not implemented for type %v", attr.Type)
+	}
+	if v == nil {
+		return fmt.Errorf("value %v of type %T can not be written as %v", attr.Value, attr.Value, attr.Type)
+	}
+	return binary.Write(w, binary.LittleEndian, v)
+}
+
+// writeLengthString writes the length of str including a null terminator followed by the null-terminated str
+func writeLengthString(w io.Writer, str string) error {
+	err := binary.Write(w, binary.LittleEndian, int32(len(str)+1))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, str+"\x00")
+	return err
+}
+
+func writeTranslatedString(w io.Writer, str TranslatedString, version FileVersion, engineVersion uint32) error {
+	var err error
+	if version >= VerBG3 || engineVersion == 0x4000001d {
+		err = binary.Write(w, binary.LittleEndian, str.Version)
+	} else {
+		err = writeLengthString(w, str.Value)
+	}
+	if err != nil {
+		return err
+	}
+	return writeLengthString(w, str.Handle)
+}
+
+func writeTranslatedFSString(w io.Writer, str TranslatedFSString, version FileVersion) error {
+	var err error
+	if version >= VerBG3 {
+		err = binary.Write(w, binary.LittleEndian, str.Version)
+	} else {
+		err = writeLengthString(w, str.Value)
+	}
+	if err != nil {
+		return err
+	}
+	err = writeLengthString(w, str.Handle)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, int32(len(str.Arguments)))
+	if err != nil {
+		return err
+	}
+	for _, arg := range str.Arguments {
+		err = writeLengthString(w, arg.Key)
+		if err != nil {
+			return err
+		}
+		err = writeTranslatedFSString(w, arg.String, version)
+		if err != nil {
+			return err
+		}
+		err = writeLengthString(w, arg.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package lslib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// namedResource returns a resource whose nodes and attributes use count distinct names
+func namedResource(count int) *Resource {
+	root := NewRegion("Region")
+	for i := 0; i < count; i++ {
+		child := &Node{Name: fmt.Sprintf("Node%d", i), Parent: root}
+		child.Attributes = []NodeAttribute{{Name: fmt.Sprintf("Attribute%d", i), Type: DT_Int, Value: int32(i)}}
+		root.Children = append(root.Children, child)
+	}
+	return &Resource{Regions: []*Node{root}}
+}
+
+func TestLSFConvertVersions(t *testing.T) {
+	tests := []struct {
+		from, to FileVersion
+	}{
+		{VerInitial, VerBG3},
+		{VerBG3, VerInitial},
+		{VerChunkedCompress, VerExtendedNodes},
+	}
+	want := namedResource(600)
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d to %d", tt.from, tt.to), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (LSFWriter{Version: tt.from}).Write(&buf, want); err != nil {
+				t.Fatal(err)
+			}
+			res, err := ReadLSF(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf.Reset()
+			if err := (LSFWriter{Version: tt.to}).Write(&buf, res); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadLSF(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Error("names or values changed in the conversion")
+			}
+		})
+	}
+}
+
+// sampleValues holds a value for every data type except matrices, translated strings only have a handle as
+// LSF files store either their value or their version depending on the file version
+var sampleValues = map[DataType]interface{}{
+	DT_Byte:               uint8(200),
+	DT_Short:              int16(-300),
+	DT_UShort:             uint16(60000),
+	DT_Int:                int32(-70000),
+	DT_UInt:               uint32(4000000000),
+	DT_Float:              float32(0.1),
+	DT_Double:             0.1,
+	DT_IVec2:              Ivec{1, -2},
+	DT_IVec3:              Ivec{1, -2, 3},
+	DT_IVec4:              Ivec{1, -2, 3, -4},
+	DT_Vec2:               Vec{0.5, -1},
+	DT_Vec3:               Vec{0.5, -1, 2},
+	DT_Vec4:               Vec{0.5, -1, 2, 0.25},
+	DT_Bool:               true,
+	DT_String:             "string",
+	DT_Path:               "Public/Shared/path.lsf",
+	DT_FixedString:        "fixed",
+	DT_LSString:           "ls string",
+	DT_ULongLong:          uint64(1) << 63,
+	DT_ScratchBuffer:      []byte{0, 1, 2, 255},
+	DT_Long:               int64(-1) << 40,
+	DT_Int8:               int8(-100),
+	DT_TranslatedString:   TranslatedString{Handle: "h1"},
+	DT_WString:            "wide",
+	DT_LSWString:          "ls wide",
+	DT_UUID:               uuid.MustParse("f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a"),
+	DT_Int64:              int64(1) << 50,
+	DT_TranslatedFSString: TranslatedFSString{TranslatedString: TranslatedString{Handle: "h2"}, Arguments: []TranslatedFSStringArgument{{Key: "k", Value: "v", String: TranslatedFSString{TranslatedString: TranslatedString{Handle: "h3"}, Arguments: []TranslatedFSStringArgument{}}}}},
+}
+
+func TestLSFRoundTripValues(t *testing.T) {
+	root := NewRegion("Region")
+	for dt := DT_None + 1; dt <= DT_Max; dt++ {
+		if dt.IsMatrix() {
+			continue
+		}
+		value, ok := sampleValues[dt]
+		if !ok {
+			t.Fatalf("no sample value for %v", dt)
+		}
+		root.Attributes = append(root.Attributes, NodeAttribute{Name: dt.String(), Type: dt, Value: value})
+	}
+	want := &Resource{Regions: []*Node{root}}
+
+	for version := VerInitial; version <= MaxVersion; version++ {
+		for _, method := range []CompressionMethod{CMNone, CMZlib} {
+			t.Run(fmt.Sprintf("version %d method %d", version, method), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := WriteLSF(&buf, want, version, method, 0); err != nil {
+					t.Fatal(err)
+				}
+				got, err := ReadLSF(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, attr := range root.Attributes {
+					a, ok := got.Regions[0].Attribute(attr.Name)
+					if !ok || !a.Equal(attr) {
+						t.Errorf("got %#v, want %#v", a, attr)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLSFWriterOmitsEmptyValues(t *testing.T) {
+	root := NewRegion("Region")
+	root.Children = []*Node{{Name: "Child", Parent: root}}
+	want := &Resource{Regions: []*Node{root}}
+	for version := VerInitial; version <= MaxVersion; version++ {
+		for _, method := range []CompressionMethod{CMNone, CMZlib, CMLZ4} {
+			t.Run(fmt.Sprintf("version %d method %d", version, method), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := WriteLSF(&buf, want, version, method, DefaultCompression); err != nil {
+					t.Fatal(err)
+				}
+				hdr, err := ReadLSFHeader(bytes.NewReader(buf.Bytes()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.ValuesUncompressedSize != 0 || hdr.ValuesSizeOnDisk != 0 {
+					t.Errorf("value section has %d bytes, %d on disk, want none", hdr.ValuesUncompressedSize, hdr.ValuesSizeOnDisk)
+				}
+				if buf.Len() != lsfHeaderSize+int(hdr.StringsSizeOnDisk+hdr.NodesSizeOnDisk+hdr.AttributesSizeOnDisk) {
+					t.Errorf("file has %d bytes, more than the header and the sections", buf.Len())
+				}
+				got, err := ReadLSF(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.Equal(want) {
+					t.Error("the resource changed in the round-trip")
+				}
+			})
+		}
+	}
+}
+
+func TestLSFWriterNameTooLong(t *testing.T) {
+	long := strings.Repeat("n", math.MaxUint16+1)
+	tests := []struct {
+		name    string
+		node    string
+		attr    string
+		wantErr bool
+	}{
+		{"longest node name", strings.Repeat("n", math.MaxUint16), "A", false},
+		{"node name", long, "A", true},
+		{"attribute name", "Node", long, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRegion("Region")
+			root.Children = []*Node{{Name: tt.node, Parent: root, Attributes: []NodeAttribute{{Name: tt.attr, Type: DT_Bool, Value: true}}}}
+			var buf bytes.Buffer
+			err := (LSFWriter{Version: VerBG3}).Write(&buf, &Resource{Regions: []*Node{root}})
+			if errors.Is(err, ErrNameTooLong) != tt.wantErr {
+				t.Fatalf("got error %v, want ErrNameTooLong: %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(err.Error()) > 200 {
+					t.Errorf("error message has %d bytes, the name should be cut", len(err.Error()))
+				}
+				return
+			}
+			res, err := ReadLSF(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Regions[0].Children[0].Name != tt.node {
+				t.Error("the longest allowed name did not round-trip")
+			}
+		})
+	}
+}
+
+func TestLSFWriterNameTableFull(t *testing.T) {
+	const name = "Overflow"
+	tests := []struct {
+		name string
+		// names is the number of names already in the bucket of name
+		names   int
+		wantErr bool
+	}{
+		{"empty bucket", 0, false},
+		{"last free slot", math.MaxUint16 - 1, false},
+		{"full bucket", math.MaxUint16, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := newLSFEncoder(VerBG3, LSMetadata{})
+			enc.names[lsfNameBucket(name)] = make([]string, tt.names)
+			err := enc.encodeNode(NewRegion(name), -1, -1)
+			if errors.Is(err, ErrNameTableFull) != tt.wantErr {
+				t.Fatalf("got error %v, want ErrNameTableFull: %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "node "+name) {
+					t.Errorf("error %q does not name the node", err)
+				}
+				return
+			}
+			// The bucket count is stored in 16 bits, a bucket at the limit must still read back
+			var buf bytes.Buffer
+			if err := enc.writeTo(&buf, CMNone, DefaultCompression); err != nil {
+				t.Fatal(err)
+			}
+			res, err := ReadLSF(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Regions[0].Name; got != name {
+				t.Errorf("got node %q, want %q", got, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+type lsjSave struct {
+	Header  lsjHeader  `json:"header"`
+	Regions *lsjObject `json:"regions"`
+}
+
+type lsjHeader struct {
+	Version string `json:"version"`
+}
+
+// lsjAttribute is an attribute in LSJ, translated strings carry their handle, version and arguments next to the value
+type lsjAttribute struct {
+	Type      string                       `json:"type"`
+	Value     interface{}                  `json:"value,omitempty"`
+	Handle    string                       `json:"handle,omitempty"`
+	Version   uint16                       `json:"version,omitempty"`
+	Arguments []TranslatedFSStringArgument `json:"arguments,omitempty"`
+}
+
+// lsjObject is a JSON object that keeps its keys in the order they were first set,
+// so that LSJ files list regions, attributes and children in the order of the Resource
+type lsjObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *lsjObject) get(key string) interface{} {
+	return o.values[key]
+}
+
+// set sets the value of key, a key that is set again keeps its position
+func (o *lsjObject) set(key string, value interface{}) {
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *lsjObject) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalLSJ returns r in Larian's LSJ JSON format. Each region is an object keyed by the region name,
+// nodes hold their attributes keyed by name and their children in arrays keyed by the child name.
+// 64-bit integers are encoded as strings and vectors as space separated strings.
+// An attribute and a child with the same name would share a key, MarshalLSJ returns an error for them.
+func (r *Resource) MarshalLSJ() ([]byte, error) {
+	save := lsjSave{
+		Header: lsjHeader{
+			Version: fmt.Sprintf("%d.%d.%d.%d", r.Metadata.MajorVersion, r.Metadata.MinorVersion, r.Metadata.Revision, r.Metadata.BuildNumber),
+		},
+		Regions: &lsjObject{},
+	}
+	for _, region := range r.Regions {
+		node, err := lsjNode(region, "")
+		if err != nil {
+			return nil, err
+		}
+		save.Regions.set(region.RegionName, node)
+	}
+	return json.MarshalIndent(struct {
+		Save lsjSave `json:"save"`
+	}{save}, "", "\t")
+}
+
+func lsjNode(n *Node, path string) (*lsjObject, error) {
+	path += "/" + n.Name
+	node := &lsjObject{}
+	for _, attr := range n.Attributes {
+		node.set(attr.Name, lsjAttributeOf(attr))
+	}
+	for _, child := range n.Children {
+		if _, ok := node.get(child.Name).(lsjAttribute); ok {
+			return nil, fmt.Errorf("%s: child %s has the name of an attribute", path[1:], child.Name)
+		}
+		c, err := lsjNode(child, path)
+		if err != nil {
+			return nil, err
+		}
+		children, _ := node.get(child.Name).([]*lsjObject)
+		node.set(child.Name, append(children, c))
+	}
+	return node, nil
+}
+
+func lsjAttributeOf(attr NodeAttribute) lsjAttribute {
+	a := lsjAttribute{
+		Type:  attr.Type.String(),
+		Value: attr.Value,
+	}
+	switch v := attr.Value.(type) {
+	case int64:
+		a.Value = strconv.FormatInt(v, 10)
+	case uint64:
+		a.Value = strconv.FormatUint(v, 10)
+	case Ivec, Vec:
+		a.Value = attr.String()
+	case TranslatedString:
+		a.Value, a.Handle, a.Version = v.Value, v.Handle, v.Version
+	case TranslatedFSString:
+		a.Value, a.Handle, a.Version, a.Arguments = v.Value, v.Handle, v.Version, v.Arguments
+	}
+	return a
+}
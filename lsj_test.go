@@ -0,0 +1,102 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMarshalLSJ(t *testing.T) {
+	id := uuid.MustParse("0d0d0d0d-1111-2222-3333-444444444444")
+	root := NewRegion("Region")
+	root.Attributes = []NodeAttribute{
+		{Name: "UUID", Type: DT_UUID, Value: id},
+		{Name: "Big", Type: DT_Int64, Value: int64(9007199254740993)},
+		{Name: "Scale", Type: DT_Float, Value: float32(1.5)},
+	}
+	data, err := (&Resource{Regions: []*Node{root}}).MarshalLSJ()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lsj struct {
+		Save struct {
+			Regions map[string]map[string]struct {
+				Type  string      `json:"type"`
+				Value interface{} `json:"value"`
+			} `json:"regions"`
+		} `json:"save"`
+	}
+	if err := json.Unmarshal(data, &lsj); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		wantType  string
+		wantValue interface{}
+	}{
+		{"UUID", "guid", id.String()},
+		{"Big", "int64", "9007199254740993"},
+		{"Scale", "float", 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr := lsj.Save.Regions["Region"][tt.name]
+			if attr.Type != tt.wantType || attr.Value != tt.wantValue {
+				t.Errorf("got %s %#v, want %s %#v", attr.Type, attr.Value, tt.wantType, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestMarshalLSJNameCollision(t *testing.T) {
+	root := NewRegion("Region")
+	item := &Node{Name: "Item", Parent: root, Attributes: []NodeAttribute{{Name: "Stats", Type: DT_LSString, Value: "x"}}}
+	item.Children = []*Node{{Name: "Stats", Parent: item}}
+	root.Children = []*Node{item}
+
+	_, err := (&Resource{Regions: []*Node{root}}).MarshalLSJ()
+	if err == nil || !strings.HasPrefix(err.Error(), "Region/Item: child Stats") {
+		t.Errorf("got error %v, want a name collision at Region/Item", err)
+	}
+}
+
+func TestMarshalLSJStableOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *Resource
+		// want lists keys in the order they must appear
+		want []string
+	}{
+		{"flat", namedResource(50), []string{`"Region"`, `"Node0"`, `"Node1"`, `"Node10"`, `"Node49"`}},
+		{"nested", nestedResource(), []string{`"Templates"`, `"uint8"`, `"int16"`, `"Item"`, `"Last"`, `"Empty": {`, `"Region"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := tt.res.MarshalLSJ()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 10; i++ {
+				again, err := tt.res.MarshalLSJ()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(first, again) {
+					t.Fatalf("write %d differs:\n%s\nfirst:\n%s", i+2, again, first)
+				}
+			}
+			last := -1
+			for _, key := range tt.want {
+				i := bytes.Index(first, []byte(key))
+				if i <= last {
+					t.Errorf("%s is not after the previous key in\n%s", key, first)
+				}
+				last = i
+			}
+		})
+	}
+}
@@ -0,0 +1,703 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+)
+
+var (
+	utf8BOM   = []byte{0xEF, 0xBB, 0xBF}
+	xmlEntity = regexp.MustCompile(`^&(?:[A-Za-z_][A-Za-z0-9_.-]*|#[0-9]+|#x[0-9A-Fa-f]+);`)
+	// emptyElement matches the elements that formatLSX closes in their start tag, encoding/xml escapes < and > in attribute values
+	emptyElement = regexp.MustCompile(`(<(?:version|attribute|node|string)(?:\s[^<>]*)?)></(?:version|attribute|node|string)>`)
+)
+
+// LenientXMLReader is an xml.Decoder that fixes known violations of the XML spec
+// found in Larian's LSX files before they are parsed
+type LenientXMLReader struct {
+	*xml.Decoder
+
+	// ReadWarnings lists the fixes that were applied to the input
+	ReadWarnings []string
+}
+
+// NewLenientXMLReader reads all of r and returns a decoder for the fixed document
+func NewLenientXMLReader(r io.Reader) (*LenientXMLReader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lr := &LenientXMLReader{}
+	lr.Decoder = xml.NewDecoder(bytes.NewReader(lr.fix(data)))
+	return lr, nil
+}
+
+func (lr *LenientXMLReader) warn(format string, a ...interface{}) {
+	var (
+		l   log.Logger
+		msg = fmt.Sprintf(format, a...)
+	)
+	l = log.With(Logger, "component", "LS converter", "file type", "lsx", "part", "lenient reader")
+	l.Log("msg", msg)
+	lr.ReadWarnings = append(lr.ReadWarnings, msg)
+}
+
+func (lr *LenientXMLReader) fix(data []byte) []byte {
+	if bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+		lr.warn("removed byte order mark")
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("<?xml")) {
+		lr.warn("missing XML declaration")
+	}
+
+	// Escape ampersands that do not start an entity or character reference
+	data, ampersands := escapeAmpersands(data)
+	if ampersands > 0 {
+		lr.warn("escaped %d unescaped ampersands", ampersands)
+	}
+
+	// Element names may not start with a number, prefix them with an underscore
+	data, elements := prefixNumericElements(data)
+	if elements > 0 {
+		lr.warn("prefixed %d element names starting with a number", elements)
+	}
+	return data
+}
+
+// escapeAmpersands escapes the ampersands in data that do not start an entity or character reference.
+// Comments, CDATA sections and processing instructions are left unchanged. It returns data itself if no
+// ampersand was escaped.
+func escapeAmpersands(data []byte) ([]byte, int) {
+	var (
+		fixed      []byte
+		ampersands = 0
+		last       = 0
+	)
+	for i := 0; i < len(data); i++ {
+		rest := data[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			i += skipPast(rest, 4, "-->")
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			i += skipPast(rest, 9, "]]>")
+		case bytes.HasPrefix(rest, []byte("<?")):
+			i += skipPast(rest, 2, "?>")
+		case data[i] == '&' && !xmlEntity.Match(rest):
+			fixed = append(fixed, data[last:i]...)
+			fixed = append(fixed, "&amp;"...)
+			last = i + 1
+			ampersands++
+		}
+	}
+	if ampersands == 0 {
+		return data, 0
+	}
+	return append(fixed, data[last:]...), ampersands
+}
+
+// prefixNumericElements prefixes the names of start and end tags in data that start with a digit with an
+// underscore. Comments, CDATA sections, processing instructions and quoted attribute values are left unchanged.
+// It returns data itself if no name was prefixed.
+func prefixNumericElements(data []byte) ([]byte, int) {
+	var (
+		fixed    []byte
+		elements = 0
+		last     = 0
+	)
+	for i := 0; i < len(data); i++ {
+		if data[i] != '<' {
+			continue
+		}
+		rest := data[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			i += skipPast(rest, 4, "-->")
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			i += skipPast(rest, 9, "]]>")
+		case bytes.HasPrefix(rest, []byte("<?")):
+			i += skipPast(rest, 2, "?>")
+		default:
+			name := 1
+			if len(rest) > 1 && rest[1] == '/' {
+				name = 2
+			}
+			if len(rest) > name && rest[name] >= '0' && rest[name] <= '9' {
+				fixed = append(fixed, data[last:i+name]...)
+				fixed = append(fixed, '_')
+				last = i + name
+				elements++
+			}
+			i += skipTag(rest)
+		}
+	}
+	if elements == 0 {
+		return data, 0
+	}
+	return append(fixed, data[last:]...), elements
+}
+
+// skipPast returns the offset in data of the last byte of the first end found at or after from, or the last offset
+// of data if end is not found
+func skipPast(data []byte, from int, end string) int {
+	if from > len(data) {
+		return len(data) - 1
+	}
+	if j := bytes.Index(data[from:], []byte(end)); j >= 0 {
+		return from + j + len(end) - 1
+	}
+	return len(data) - 1
+}
+
+// skipTag returns the offset in data of the > closing the tag it starts with, a > in a quoted attribute value does
+// not close the tag. The last offset of data is returned if the tag is not closed.
+func skipTag(data []byte) int {
+	var quote byte
+	for i := 1; i < len(data); i++ {
+		switch b := data[i]; {
+		case quote != 0:
+			if b == quote {
+				quote = 0
+			}
+		case b == '"' || b == '\'':
+			quote = b
+		case b == '>':
+			return i
+		}
+	}
+	return len(data) - 1
+}
+
+// LSXReader reads LSX documents, known violations of the XML spec are fixed as by NewLenientXMLReader.
+// The whole document is read into memory to be fixed.
+type LSXReader struct {
+	// PreserveExtraAttrs keeps unknown attributes of attribute elements in NodeAttribute.ExtraAttrs,
+	// otherwise they are dropped
+	PreserveExtraAttrs bool
+
+	// DefaultType is the type of attribute elements without a type attribute, such as DT_String for
+	// hand-written snippets. If it is DT_None these elements are an error.
+	DefaultType DataType
+}
+
+// Read reads an LSX document from r
+func (lr LSXReader) Read(r io.Reader) (*Resource, error) {
+	res, _, err := lr.ReadWithWarnings(r)
+	return res, err
+}
+
+// ReadWithWarnings reads an LSX document from r like Read, and also returns the fixes that were applied to the
+// document before it was parsed as listed in LenientXMLReader.ReadWarnings
+func (lr LSXReader) ReadWithWarnings(r io.Reader) (*Resource, []string, error) {
+	res := &Resource{}
+	warnings, err := lr.Walk(r, &lsxTreeBuilder{res: res, preserveExtraAttrs: lr.PreserveExtraAttrs})
+	if err != nil {
+		return nil, warnings, err
+	}
+	return res, warnings, nil
+}
+
+// Walk is WalkLSX, known violations of the XML spec are fixed first and the fixes are returned as by
+// ReadWithWarnings. Unlike WalkLSX, the whole document is held in memory while it is walked.
+func (lr LSXReader) Walk(r io.Reader, h NodeHandler) ([]string, error) {
+	return lsxWalker{h: h, defaultType: lr.DefaultType, lenient: true}.walk(r)
+}
+
+// NodeHandler receives the regions, nodes and attributes of an LSX document from WalkLSX in document order.
+// OnAttribute is called for the attributes of the node started by the last OnNodeStart call that has not ended,
+// nodes started while another node is open are its children.
+type NodeHandler interface {
+	OnRegion(id string)
+	OnNodeStart(name string)
+	OnAttribute(attr NodeAttribute)
+	OnNodeEnd()
+	OnRegionEnd()
+}
+
+// VersionHandler can be implemented by a NodeHandler to also receive the version element of the document
+type VersionHandler interface {
+	OnVersion(metadata LSMetadata)
+}
+
+// WalkLSX reads an LSX document from r and passes its contents to h as they are parsed, without building a tree
+// of nodes or holding the document in memory. The document must be valid XML, use LSXReader.Walk to fix known
+// violations of the XML spec first. Only the first node element of a region is read, as by ReadLSX.
+func WalkLSX(r io.Reader, h NodeHandler) error {
+	_, err := lsxWalker{h: h}.walk(r)
+	return err
+}
+
+// lsxWalker passes the contents of an LSX document to h
+type lsxWalker struct {
+	h NodeHandler
+	// defaultType is added as the type of attribute elements without one unless it is DT_None
+	defaultType DataType
+	// lenient reads the document through NewLenientXMLReader, which reads all of it into memory
+	lenient bool
+}
+
+func (w lsxWalker) walk(r io.Reader) ([]string, error) {
+	if !w.lenient {
+		return nil, w.document(xml.NewDecoder(r))
+	}
+	xr, err := NewLenientXMLReader(r)
+	if err != nil {
+		return nil, err
+	}
+	err = w.document(xr.Decoder)
+	return xr.ReadWarnings, err
+}
+
+// document passes the contents of the save element of the document read by d to w.h
+func (w lsxWalker) document(d *xml.Decoder) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		if _, ok := t.(xml.StartElement); ok {
+			return w.save(d)
+		}
+	}
+}
+
+// save passes the contents of the save element whose start element was just read from d to w.h
+func (w lsxWalker) save(d *xml.Decoder) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "version":
+				var metadata LSMetadata
+				err = d.DecodeElement(&metadata, &t)
+				if vh, ok := w.h.(VersionHandler); ok && err == nil {
+					vh.OnVersion(metadata)
+				}
+
+			case "region":
+				err = w.region(d, t)
+
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (w lsxWalker) region(d *xml.Decoder, start xml.StartElement) error {
+	var (
+		id      string
+		hasRoot bool
+	)
+	for _, a := range start.Attr {
+		if a.Name.Local == "id" {
+			id = a.Value
+		}
+	}
+	w.h.OnRegion(id)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "node" || hasRoot {
+				err = d.Skip()
+			} else {
+				hasRoot = true
+				err = w.node(d, t)
+			}
+			if err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			w.h.OnRegionEnd()
+			return nil
+		}
+	}
+}
+
+// node passes the node element start, its attributes and the nodes in its children element to w.h
+func (w lsxWalker) node(d *xml.Decoder, start xml.StartElement) error {
+	var name string
+	for _, a := range start.Attr {
+		if a.Name.Local == "id" {
+			name = a.Value
+		}
+	}
+	w.h.OnNodeStart(name)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "attribute":
+				var attr NodeAttribute
+				err = d.DecodeElement(&attr, w.withDefaultType(t))
+				if err == nil {
+					w.h.OnAttribute(attr)
+				}
+
+			case "children":
+				err = w.children(d)
+
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			w.h.OnNodeEnd()
+			return nil
+		}
+	}
+}
+
+func (w lsxWalker) children(d *xml.Decoder) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "node" {
+				err = d.Skip()
+			} else {
+				err = w.node(d, t)
+			}
+			if err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// withDefaultType returns the attribute element start with w.defaultType added if it has no type
+func (w lsxWalker) withDefaultType(start xml.StartElement) *xml.StartElement {
+	if w.defaultType == DT_None {
+		return &start
+	}
+	for _, a := range start.Attr {
+		if a.Name.Local == "type" {
+			return &start
+		}
+	}
+	start.Attr = append(start.Attr[:len(start.Attr):len(start.Attr)], xml.Attr{
+		Name:  xml.Name{Local: "type"},
+		Value: w.defaultType.String(),
+	})
+	return &start
+}
+
+// lsxTreeBuilder is a NodeHandler that builds the tree of nodes of a document. The root nodes of regions are added
+// to res, a node outside of a region is read into root.
+type lsxTreeBuilder struct {
+	res                *Resource
+	root               *Node
+	preserveExtraAttrs bool
+
+	region   string
+	inRegion bool
+	nodes    []*Node
+}
+
+func (b *lsxTreeBuilder) OnVersion(metadata LSMetadata) {
+	b.res.Metadata = metadata
+}
+
+func (b *lsxTreeBuilder) OnRegion(id string) {
+	b.region, b.inRegion = id, true
+}
+
+func (b *lsxTreeBuilder) OnNodeStart(name string) {
+	var n *Node
+	switch {
+	case len(b.nodes) > 0:
+		n = &Node{Name: name}
+		b.nodes[len(b.nodes)-1].AppendChild(n)
+
+	case b.inRegion:
+		n = NewRegion(b.region)
+		n.Name = name
+		b.res.Regions = append(b.res.Regions, n)
+
+	default:
+		n = b.root
+		n.Name = name
+	}
+	b.nodes = append(b.nodes, n)
+}
+
+func (b *lsxTreeBuilder) OnAttribute(attr NodeAttribute) {
+	if !b.preserveExtraAttrs {
+		attr.ExtraAttrs = nil
+	}
+	n := b.nodes[len(b.nodes)-1]
+	n.Attributes = append(n.Attributes, attr)
+}
+
+func (b *lsxTreeBuilder) OnNodeEnd() {
+	b.nodes = b.nodes[:len(b.nodes)-1]
+}
+
+func (b *lsxTreeBuilder) OnRegionEnd() {
+	b.region, b.inRegion = "", false
+}
+
+// ReadLSX reads an LSX document from r using the default LSXReader
+func ReadLSX(r io.Reader) (*Resource, error) {
+	return LSXReader{}.Read(r)
+}
+
+// WriteLSX writes res to w as an LSX document
+func WriteLSX(w io.Writer, res *Resource) error {
+	return LSXWriter{}.Write(w, res)
+}
+
+// MarshalLSX writes r to w as an LSX document, it is the same as WriteLSX(w, r)
+func (r *Resource) MarshalLSX(w io.Writer) error {
+	return WriteLSX(w, r)
+}
+
+// LSXWriter writes a Resource as an LSX document
+type LSXWriter struct {
+	// RejectNilUUID makes Write fail when a DT_UUID attribute holds uuid.Nil,
+	// an unset UUID is almost always an authoring mistake
+	RejectNilUUID bool
+
+	// IntentionalNilUUID lists the names of attributes that may hold uuid.Nil when RejectNilUUID is set
+	IntentionalNilUUID map[string]bool
+
+	// OmitZeroValues leaves out attributes that hold the zero value of their type
+	OmitZeroValues bool
+
+	// MinifyOutput writes the document without indentation or line breaks and closes empty elements with "/>"
+	MinifyOutput bool
+
+	// HexAttributes lists the names of integer attributes whose value is written in hexadecimal, e.g. "0x1f"
+	HexAttributes map[string]bool
+}
+
+// lsxRegion writes a region using the options of an LSXWriter
+type lsxRegion struct {
+	*Node
+	opts MarshalXMLOptions
+}
+
+func (r lsxRegion) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return r.MarshalXMLWithOpts(e, start, r.opts)
+}
+
+// Write writes r to w as an LSX document
+func (lw LSXWriter) Write(w io.Writer, r *Resource) error {
+	var (
+		v   []byte
+		err error
+	)
+	if lw.RejectNilUUID {
+		err = checkNilUUID(r, lw.IntentionalNilUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	regions := make([]lsxRegion, len(r.Regions))
+	for i, region := range r.Regions {
+		regions[i] = lsxRegion{region, MarshalXMLOptions{
+			OmitZeroValues: lw.OmitZeroValues,
+			HexAttributes:  lw.HexAttributes,
+		}}
+	}
+	save := struct {
+		XMLName  string     `xml:"save"`
+		Metadata LSMetadata `xml:"version"`
+		Regions  []lsxRegion
+	}{"", r.Metadata, regions}
+	header := strings.ToLower(xml.Header)
+	if lw.MinifyOutput {
+		v, err = xml.Marshal(save)
+		header = strings.TrimSuffix(header, "\n")
+	} else {
+		v, err = xml.MarshalIndent(save, "", "\t")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, header)
+	if err != nil {
+		return err
+	}
+	if lw.MinifyOutput {
+		_, err = io.WriteString(w, formatLSX(v, "/>"))
+		return err
+	}
+	_, err = io.WriteString(w, formatLSX(v, " />")+"\n")
+	return err
+}
+
+// NodeWriter writes regions, nodes and attributes to an xml.Encoder as they are produced, without building a Node tree.
+// It keeps track of the open elements so that every start element is closed in order and the children element
+// is written before the first child of a node. Unlike LSXWriter the output is not converted to the formatting
+// used by Larian's tools, and the caller writes the save and version elements around the regions.
+type NodeWriter struct {
+	e     *xml.Encoder
+	stack []nodeWriterElement
+}
+
+type nodeWriterElement struct {
+	start xml.StartElement
+	// hasChildren is set once a node has a children element or a region has its root node
+	hasChildren bool
+}
+
+// NewNodeWriter returns a NodeWriter that writes to e
+func NewNodeWriter(e *xml.Encoder) *NodeWriter {
+	return &NodeWriter{e: e}
+}
+
+func (nw *NodeWriter) top() *nodeWriterElement {
+	if len(nw.stack) == 0 {
+		return nil
+	}
+	return &nw.stack[len(nw.stack)-1]
+}
+
+func (nw *NodeWriter) push(name, id string) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: name},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: id}},
+	}
+	nw.stack = append(nw.stack, nodeWriterElement{start: start})
+	return nw.e.EncodeToken(start)
+}
+
+func (nw *NodeWriter) pop(name string) error {
+	top := nw.top()
+	if top == nil || top.start.Name.Local != name {
+		return fmt.Errorf("no %s is open", name)
+	}
+	if name == "node" && top.hasChildren {
+		err := nw.e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "children"}})
+		if err != nil {
+			return err
+		}
+	}
+	nw.stack = nw.stack[:len(nw.stack)-1]
+	return nw.e.EncodeToken(top.start.End())
+}
+
+// WriteRegionStart starts the region id, regions can not be nested
+func (nw *NodeWriter) WriteRegionStart(id string) error {
+	if len(nw.stack) != 0 {
+		return fmt.Errorf("region %s: regions can only be started at the top level", id)
+	}
+	return nw.push("region", id)
+}
+
+// WriteNodeStart starts a node named name, as the root node of the current region or as a child of the current node
+func (nw *NodeWriter) WriteNodeStart(name string) error {
+	top := nw.top()
+	switch {
+	case top == nil:
+		return fmt.Errorf("node %s: nodes must be inside a region", name)
+
+	case top.start.Name.Local == "region":
+		if top.hasChildren {
+			return fmt.Errorf("node %s: a region has a single root node", name)
+		}
+
+	case !top.hasChildren:
+		err := nw.e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "children"}})
+		if err != nil {
+			return err
+		}
+	}
+	top.hasChildren = true
+	return nw.push("node", name)
+}
+
+// WriteAttribute writes attr to the current node, attributes must be written before the children of the node
+func (nw *NodeWriter) WriteAttribute(attr NodeAttribute) error {
+	top := nw.top()
+	if top == nil || top.start.Name.Local != "node" {
+		return fmt.Errorf("attribute %s: attributes must be inside a node", attr.Name)
+	}
+	if top.hasChildren {
+		return fmt.Errorf("attribute %s: attributes must be written before the children of a node", attr.Name)
+	}
+	return nw.e.EncodeElement(attr, xml.StartElement{Name: xml.Name{Local: "attribute"}})
+}
+
+// WriteNodeEnd ends the current node
+func (nw *NodeWriter) WriteNodeEnd() error {
+	return nw.pop("node")
+}
+
+// WriteRegionEnd ends the current region, its nodes must have been ended
+func (nw *NodeWriter) WriteRegionEnd() error {
+	return nw.pop("region")
+}
+
+// Flush flushes the underlying encoder
+func (nw *NodeWriter) Flush() error {
+	return nw.e.Flush()
+}
+
+// formatLSX converts the output of encoding/xml to the formatting used by Larian's tools,
+// elements without content are closed with emptyEnd
+func formatLSX(v []byte, emptyEnd string) string {
+	n := emptyElement.ReplaceAllString(string(v), "$1"+emptyEnd)
+	n = strings.ReplaceAll(n, "&#39;", "'")
+	return n
+}
+
+// MarshalNodeFragment returns n and its children as an LSX <node> element without the save and region elements,
+// the region of n is ignored
+func MarshalNodeFragment(n *Node) ([]byte, error) {
+	fragment := *n
+	fragment.RegionName = ""
+	v, err := xml.MarshalIndent(fragment, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(formatLSX(v, " />")), nil
+}
+
+// UnmarshalNodeFragment reads a <node> element written by MarshalNodeFragment, the returned node has no parent
+func UnmarshalNodeFragment(data []byte) (*Node, error) {
+	n := &Node{}
+	err := xml.Unmarshal(data, n)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
@@ -0,0 +1,651 @@
+package lslib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPrefixNumericElements(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		want     string
+		elements int
+	}{
+		{"none", `<a b="1">text</a>`, `<a b="1">text</a>`, 0},
+		{"start and end tag", `<1a></1a>`, `<_1a></_1a>`, 2},
+		{"empty element", `<a><2b/></a>`, `<a><_2b/></a>`, 1},
+		{"comment", `<!-- <1a> --><a/>`, `<!-- <1a> --><a/>`, 0},
+		{"cdata", `<a><![CDATA[<1a></1a>]]></a>`, `<a><![CDATA[<1a></1a>]]></a>`, 0},
+		{"processing instruction", `<?pi <1a>?><a/>`, `<?pi <1a>?><a/>`, 0},
+		{"attribute value", `<a v="x<1y" w='>'><3c/></a>`, `<a v="x<1y" w='>'><_3c/></a>`, 1},
+		{"unterminated comment", `<!-- <1a>`, `<!-- <1a>`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, elements := prefixNumericElements([]byte(tt.in))
+			if string(got) != tt.want || elements != tt.elements {
+				t.Errorf("got %q, %d, want %q, %d", got, elements, tt.want, tt.elements)
+			}
+		})
+	}
+}
+
+func TestEscapeAmpersands(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		want       string
+		ampersands int
+	}{
+		{"none", `<a b="1">text</a>`, `<a b="1">text</a>`, 0},
+		{"attribute value", `<a b="x & y"/>`, `<a b="x &amp; y"/>`, 1},
+		{"text", `<a>x & y</a>`, `<a>x &amp; y</a>`, 1},
+		{"references", `<a b="&amp; &#38; &#x26;">&lt;</a>`, `<a b="&amp; &#38; &#x26;">&lt;</a>`, 0},
+		{"cdata", `<a><![CDATA[x & y &amp]]> & </a>`, `<a><![CDATA[x & y &amp]]> &amp; </a>`, 1},
+		{"comment", `<!-- x & y --><a/>`, `<!-- x & y --><a/>`, 0},
+		{"processing instruction", `<?pi x & y?><a/>`, `<?pi x & y?><a/>`, 0},
+		{"unterminated cdata", `<a><![CDATA[x & y`, `<a><![CDATA[x & y`, 0},
+		{"trailing ampersand", `<a/>&`, `<a/>&amp;`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ampersands := escapeAmpersands([]byte(tt.in))
+			if string(got) != tt.want || ampersands != tt.ampersands {
+				t.Errorf("got %q, %d, want %q, %d", got, ampersands, tt.want, tt.ampersands)
+			}
+		})
+	}
+}
+
+func TestLSXReaderWarnings(t *testing.T) {
+	const doc = "\xEF\xBB\xBF<save><version major=\"4\" minor=\"0\" revision=\"9\" build=\"322\"/>" +
+		"<region id=\"Region\"><node id=\"Region\">" +
+		"<attribute id=\"Name\" type=\"LSString\" value=\"a & b\"/>" +
+		"<!-- <1comment> --></node></region></save>"
+
+	res, warnings, err := LSXReader{}.ReadWithWarnings(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"removed byte order mark", "missing XML declaration", "escaped 1 unescaped ampersands"}
+	if strings.Join(warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+	attr, ok := res.Regions[0].Attribute("Name")
+	if !ok || attr.Value != "a & b" {
+		t.Errorf("got attribute %#v, want a & b", attr)
+	}
+}
+
+// recordingHandler records the events of WalkLSX and how much of the document had been read at the first node
+type recordingHandler struct {
+	events []string
+	read   *countingReader
+	// readAtFirstNode is the number of bytes read from the document when the first node started
+	readAtFirstNode int
+}
+
+func (h *recordingHandler) OnRegion(id string) { h.events = append(h.events, "region "+id) }
+func (h *recordingHandler) OnNodeStart(name string) {
+	if h.readAtFirstNode == 0 {
+		h.readAtFirstNode = h.read.n
+	}
+	h.events = append(h.events, "node "+name)
+}
+func (h *recordingHandler) OnAttribute(attr NodeAttribute) {
+	h.events = append(h.events, "attribute "+attr.Name+"="+attr.String())
+}
+func (h *recordingHandler) OnNodeEnd()   { h.events = append(h.events, "end node") }
+func (h *recordingHandler) OnRegionEnd() { h.events = append(h.events, "end region") }
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestWalkLSX(t *testing.T) {
+	var large bytes.Buffer
+	if err := WriteLSX(&large, namedResource(2000)); err != nil {
+		t.Fatal(err)
+	}
+	const (
+		valid = `<save><region id="R"><node id="R"><attribute id="A" type="int32" value="1"/>` +
+			`<children><node id="C"/></children></node></region></save>`
+		ampersand = `<save><region id="R"><node id="R"><attribute id="A" type="LSString" value="a & b"/></node></region></save>`
+	)
+	tests := []struct {
+		name    string
+		doc     string
+		lenient bool
+		// events are compared only if they are set
+		events  []string
+		wantErr bool
+		// streamed is whether the first node must start before the whole document is read
+		streamed bool
+	}{
+		{"valid", valid, false, []string{"region R", "node R", "attribute A=1", "node C", "end node", "end node", "end region"}, false, false},
+		{"byte order mark", "\ufeff" + valid, false, []string{"region R", "node R", "attribute A=1", "node C", "end node", "end node", "end region"}, false, false},
+		{"unescaped ampersand", ampersand, false, nil, true, false},
+		{"lenient unescaped ampersand", ampersand, true, []string{"region R", "node R", "attribute A=a & b", "end node", "end region"}, false, false},
+		{"large document", large.String(), false, nil, false, true},
+		{"lenient large document", large.String(), true, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &countingReader{r: strings.NewReader(tt.doc)}
+			h := &recordingHandler{read: r}
+			var err error
+			if tt.lenient {
+				_, err = LSXReader{}.Walk(r, h)
+			} else {
+				err = WalkLSX(r, h)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.events != nil && strings.Join(h.events, "\n") != strings.Join(tt.events, "\n") {
+				t.Errorf("got events %q, want %q", h.events, tt.events)
+			}
+			if streamed := h.readAtFirstNode < len(tt.doc); !tt.wantErr && streamed != tt.streamed {
+				t.Errorf("read %d of %d bytes before the first node, want streamed %v", h.readAtFirstNode, len(tt.doc), tt.streamed)
+			}
+		})
+	}
+}
+
+func TestLSXWriterRejectNilUUID(t *testing.T) {
+	newResource := func() *Resource {
+		root := NewRegion("Region")
+		child := &Node{Name: "Child", Parent: root}
+		child.Attributes = []NodeAttribute{{Name: "MapKey", Type: DT_UUID, Value: uuid.Nil}}
+		root.Children = []*Node{child}
+		return &Resource{Regions: []*Node{root}}
+	}
+	tests := []struct {
+		name    string
+		writer  LSXWriter
+		wantErr bool
+	}{
+		{"disabled", LSXWriter{}, false},
+		{"enabled", LSXWriter{RejectNilUUID: true}, true},
+		{"intentional", LSXWriter{RejectNilUUID: true, IntentionalNilUUID: map[string]bool{"MapKey": true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.writer.Write(&bytes.Buffer{}, newResource())
+			if errors.Is(err, ErrNilUUID) != tt.wantErr {
+				t.Fatalf("got error %v, want ErrNilUUID: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.HasPrefix(err.Error(), "Region/Child: attribute MapKey:") {
+				t.Errorf("error %q does not start with the path of the attribute", err)
+			}
+		})
+	}
+}
+
+func TestWriteLSXBooleans(t *testing.T) {
+	root := NewRegion("Region")
+	root.Attributes = []NodeAttribute{
+		{Name: "Yes", Type: DT_Bool, Value: true},
+		{Name: "No", Type: DT_Bool, Value: false},
+		{Name: "Text", Type: DT_LSString, Value: "true or false"},
+	}
+	var buf bytes.Buffer
+	if err := WriteLSX(&buf, &Resource{Regions: []*Node{root}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`id="Yes" type="bool" value="True"`, `id="No" type="bool" value="False"`, `value="true or false"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s is missing from\n%s", want, buf.String())
+		}
+	}
+
+	res, err := ReadLSX(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, attr := range root.Attributes {
+		got, ok := res.Regions[0].Attribute(attr.Name)
+		if !ok || !got.Equal(attr) {
+			t.Errorf("got %#v, want %#v", got, attr)
+		}
+	}
+}
+
+func TestWriteLSXTranslatedFSString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value TranslatedFSString
+		want  string
+	}{
+		{
+			"two arguments",
+			TranslatedFSString{
+				TranslatedString: TranslatedString{Handle: "h1", Version: 1},
+				Arguments: []TranslatedFSStringArgument{
+					{Key: "Name", Value: "1", String: TranslatedFSString{TranslatedString: TranslatedString{Handle: "h2", Version: 2}}},
+					{Key: "Count", Value: "2", String: TranslatedFSString{TranslatedString: TranslatedString{Handle: "h3", Value: "x"}}},
+				},
+			},
+			`<attribute id="Text" type="TranslatedFSString" handle="h1" version="1" arguments="2"><arguments>` +
+				`<argument key="Name" value="1"><string handle="h2" version="2" arguments="0"/></argument>` +
+				`<argument key="Count" value="2"><string handle="h3" value="x" version="0" arguments="0"/></argument>` +
+				`</arguments></attribute>`,
+		},
+		{
+			"no arguments",
+			TranslatedFSString{TranslatedString: TranslatedString{Handle: "h1"}},
+			`<attribute id="Text" type="TranslatedFSString" handle="h1" version="0" arguments="0"/>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRegion("Region")
+			root.Attributes = []NodeAttribute{{Name: "Text", Type: DT_TranslatedFSString, Value: tt.value}}
+			var buf bytes.Buffer
+			if err := (LSXWriter{MinifyOutput: true}).Write(&buf, &Resource{Regions: []*Node{root}}); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Fatalf("%s is missing from\n%s", tt.want, buf.String())
+			}
+			res, err := ReadLSX(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := res.Regions[0].Attribute("Text"); got == nil || !got.Equal(root.Attributes[0]) {
+				t.Errorf("got %#v, want %#v", got, root.Attributes[0])
+			}
+		})
+	}
+}
+
+func TestNodeFragmentRoundTrip(t *testing.T) {
+	root := NewRegion("Region")
+	n := &Node{Name: "Item", Parent: root}
+	n.Attributes = []NodeAttribute{
+		{Name: "Name", Type: DT_LSString, Value: "true <sword> & shield"},
+		{Name: "Stack", Type: DT_Int, Value: int32(3)},
+		{Name: "Equipped", Type: DT_Bool, Value: false},
+	}
+	empty := &Node{Name: "Empty", Parent: n}
+	child := &Node{Name: "Rune", Parent: n, Attributes: []NodeAttribute{{Name: "Offset", Type: DT_Vec3, Value: Vec{1, 2.5, -3}}}}
+	grandchild := &Node{Name: "Glow", Parent: child, Attributes: []NodeAttribute{{Name: "Color", Type: DT_IVec4, Value: Ivec{255, 0, 0, 255}}}}
+	child.Children = []*Node{grandchild}
+	n.Children = []*Node{empty, child}
+	root.Children = []*Node{n}
+
+	data, err := MarshalNodeFragment(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(`<node id="Item">`)) || !bytes.Contains(data, []byte("<children>")) {
+		t.Errorf("fragment is not a node element with children:\n%s", data)
+	}
+	got, err := UnmarshalNodeFragment(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Parent != nil || got.RegionName != "" {
+		t.Errorf("got parent %v and region %q, want none", got.Parent, got.RegionName)
+	}
+	if !got.DeepEqual(n, DeepEqualOptions{}) {
+		t.Errorf("fragment did not round-trip:\n%s", data)
+	}
+	if got.Children[1].Children[0].Parent != got.Children[1] {
+		t.Error("the parent of a grandchild is not set")
+	}
+}
+
+func TestMarshalLSXGolden(t *testing.T) {
+	golden, err := os.ReadFile("testdata/meta.lsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ReadLSX(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Regions[0].Name != "root" || res.Regions[0].RegionName != "Config" {
+		t.Errorf("got region %s with root node %s", res.Regions[0].RegionName, res.Regions[0].Name)
+	}
+	var buf bytes.Buffer
+	if err := res.MarshalLSX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != strings.TrimSpace(string(golden)) {
+		t.Errorf("got\n%s\nwant\n%s", got, golden)
+	}
+}
+
+func TestLSXPreserveExtraAttrs(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="utf-8"?><save><version major="4" minor="0" revision="9" build="322"/>` +
+		`<region id="Region"><node id="Region">` +
+		`<attribute id="Name" type="LSString" value="Sword" note="renamed by a tool"/>` +
+		`</node></region></save>`
+	tests := []struct {
+		name     string
+		preserve bool
+		want     bool
+	}{
+		{"dropped", false, false},
+		{"preserved", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := LSXReader{PreserveExtraAttrs: tt.preserve}.Read(strings.NewReader(doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := WriteLSX(&buf, res); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(buf.String(), `note="renamed by a tool"`); got != tt.want {
+				t.Errorf("note written: %v, want %v\n%s", got, tt.want, buf.String())
+			}
+			attr, ok := res.Regions[0].Attribute("Name")
+			if !ok || attr.Value != "Sword" {
+				t.Errorf("got attribute %#v, want Sword", attr)
+			}
+		})
+	}
+}
+
+func TestNodeWriter(t *testing.T) {
+	type step func(nw *NodeWriter) error
+	var (
+		region    = func(id string) step { return func(nw *NodeWriter) error { return nw.WriteRegionStart(id) } }
+		regionEnd = func(nw *NodeWriter) error { return nw.WriteRegionEnd() }
+		node      = func(name string) step { return func(nw *NodeWriter) error { return nw.WriteNodeStart(name) } }
+		nodeEnd   = func(nw *NodeWriter) error { return nw.WriteNodeEnd() }
+		attribute = func(name string) step {
+			return func(nw *NodeWriter) error {
+				return nw.WriteAttribute(NodeAttribute{Name: name, Type: DT_Int, Value: int32(1)})
+			}
+		}
+	)
+	tests := []struct {
+		name    string
+		steps   []step
+		want    string
+		wantErr bool
+	}{
+		{
+			"nested",
+			[]step{region("R"), node("R"), attribute("A"), node("C"), nodeEnd, node("D"), attribute("B"), nodeEnd, nodeEnd, regionEnd},
+			`<region id="R"><node id="R"><attribute id="A" type="int32" value="1"></attribute><children>` +
+				`<node id="C"></node><node id="D"><attribute id="B" type="int32" value="1"></attribute></node>` +
+				`</children></node></region>`,
+			false,
+		},
+		{"node outside a region", []step{node("N")}, "", true},
+		{"nested regions", []step{region("R"), region("S")}, "", true},
+		{"second root node", []step{region("R"), node("R"), nodeEnd, node("S")}, "", true},
+		{"attribute after children", []step{region("R"), node("R"), node("C"), nodeEnd, attribute("A")}, "", true},
+		{"attribute in a region", []step{region("R"), attribute("A")}, "", true},
+		{"region ended with an open node", []step{region("R"), node("R"), regionEnd}, "", true},
+		{"unbalanced node end", []step{region("R"), nodeEnd}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				buf bytes.Buffer
+				nw  = NewNodeWriter(xml.NewEncoder(&buf))
+				err error
+			)
+			for _, s := range tt.steps {
+				if err = s(nw); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := nw.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkNodeWriter writes a generated tree of 100 000 nodes, either as it is generated through a NodeWriter
+// or by building the tree and writing it with WriteLSX
+func BenchmarkNodeWriter(b *testing.B) {
+	const parents, children = 1000, 99
+	attr := func(i int) NodeAttribute {
+		return NodeAttribute{Name: "Index", Type: DT_Int, Value: int32(i)}
+	}
+	b.Run("NodeWriter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e := xml.NewEncoder(io.Discard)
+			e.Indent("", "\t")
+			nw := NewNodeWriter(e)
+			err := nw.WriteRegionStart("Region")
+			if err == nil {
+				err = nw.WriteNodeStart("Region")
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			for p := 0; p < parents; p++ {
+				err = nw.WriteNodeStart("Parent")
+				if err == nil {
+					err = nw.WriteAttribute(attr(p))
+				}
+				for c := 0; c < children && err == nil; c++ {
+					err = nw.WriteNodeStart("Child")
+					if err == nil {
+						err = nw.WriteAttribute(attr(c))
+					}
+					if err == nil {
+						err = nw.WriteNodeEnd()
+					}
+				}
+				if err == nil {
+					err = nw.WriteNodeEnd()
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			err = nw.WriteNodeEnd()
+			if err == nil {
+				err = nw.WriteRegionEnd()
+			}
+			if err == nil {
+				err = nw.Flush()
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Tree", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			root := NewRegion("Region")
+			for p := 0; p < parents; p++ {
+				parent := &Node{Name: "Parent", Parent: root, Attributes: []NodeAttribute{attr(p)}}
+				for c := 0; c < children; c++ {
+					parent.Children = append(parent.Children, &Node{Name: "Child", Parent: parent, Attributes: []NodeAttribute{attr(c)}})
+				}
+				root.Children = append(root.Children, parent)
+			}
+			if err := WriteLSX(io.Discard, &Resource{Regions: []*Node{root}}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestLSXWriterMinifyOutput(t *testing.T) {
+	want := nestedResource()
+	var buf bytes.Buffer
+	if err := (LSXWriter{MinifyOutput: true}).Write(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"\n", "\t", " />", "> <"} {
+		if strings.Contains(buf.String(), s) {
+			t.Errorf("minified output contains %q:\n%s", s, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), `<node id="Empty"/>`) {
+		t.Errorf("empty node is not closed with />:\n%s", buf.String())
+	}
+	got, err := ReadLSX(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := WriteLSX(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	indented, err := ReadLSX(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(indented) {
+		t.Error("minified output does not read back as the indented output")
+	}
+}
+
+func TestLSXReaderDefaultType(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="utf-8"?><save><version major="4" minor="0" revision="9" build="322"/>` +
+		`<region id="Region"><node id="Region">` +
+		`<attribute id="Untyped" value="text"/>` +
+		`<attribute id="Typed" type="int32" value="5"/>` +
+		`</node></region></save>`
+	tests := []struct {
+		name        string
+		defaultType DataType
+		want        NodeAttribute
+		wantErr     error
+	}{
+		{"no default", DT_None, NodeAttribute{}, ErrMissingType},
+		{"string", DT_String, NodeAttribute{Name: "Untyped", Type: DT_String, Value: "text"}, nil},
+		{"fixed string", DT_FixedString, NodeAttribute{Name: "Untyped", Type: DT_FixedString, Value: "text"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := LSXReader{DefaultType: tt.defaultType}.Read(strings.NewReader(doc))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if !strings.Contains(err.Error(), "Untyped") {
+					t.Errorf("error %q does not name the attribute", err)
+				}
+				return
+			}
+			if got, ok := res.Regions[0].Attribute("Untyped"); !ok || !got.Equal(tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+			if got, ok := res.Regions[0].Attribute("Typed"); !ok || got.Type != DT_Int {
+				t.Errorf("the type of a typed attribute was replaced: %#v", got)
+			}
+		})
+	}
+}
+
+func TestLSXWriterHexAttributes(t *testing.T) {
+	root := NewRegion("Region")
+	root.Attributes = []NodeAttribute{
+		{Name: "Flags", Type: DT_UInt, Value: uint32(0xdeadbeef)},
+		{Name: "Mask", Type: DT_Byte, Value: uint8(0x1f)},
+		{Name: "Signed", Type: DT_Int, Value: int32(-16)},
+		{Name: "Decimal", Type: DT_UInt, Value: uint32(31)},
+		{Name: "Name", Type: DT_LSString, Value: "31"},
+	}
+	writer := LSXWriter{HexAttributes: map[string]bool{"Flags": true, "Mask": true, "Signed": true, "Name": true}}
+	var buf bytes.Buffer
+	if err := writer.Write(&buf, &Resource{Regions: []*Node{root}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`id="Flags" type="uint32" value="0xdeadbeef"`,
+		`id="Mask" type="uint8" value="0x1f"`,
+		`id="Decimal" type="uint32" value="31"`,
+		`id="Name" type="LSString" value="31"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s is missing from\n%s", want, buf.String())
+		}
+	}
+
+	res, err := ReadLSX(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range root.Attributes {
+		if got, ok := res.Regions[0].Attribute(want.Name); !ok || !got.Equal(want) {
+			t.Errorf("got %#v, want %#v", got, want)
+		}
+	}
+}
+
+func TestLSXChildrenElement(t *testing.T) {
+	root := NewRegion("Region")
+	parent := &Node{Name: "Parent", Parent: root}
+	child := &Node{Name: "Child", Parent: parent, Attributes: []NodeAttribute{{Name: "A", Type: DT_Int, Value: int32(1)}}}
+	leaf := &Node{Name: "Leaf", Parent: child}
+	child.Children = []*Node{leaf}
+	parent.Children = []*Node{child}
+	root.Children = []*Node{parent}
+
+	var buf bytes.Buffer
+	if err := (LSXWriter{MinifyOutput: true}).Write(&buf, &Resource{Regions: []*Node{root}}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<region id="Region"><node id="Region"><children><node id="Parent"><children><node id="Child">` +
+		`<attribute id="A" type="int32" value="1"/><children><node id="Leaf"/></children></node>` +
+		`</children></node></children></node></region>`
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"written", buf.String()},
+		{"empty children element", strings.Replace(buf.String(), `<node id="Leaf"/>`, `<node id="Leaf"><children></children></node>`, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ReadLSX(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := res.Regions[0]
+			if !got.DeepEqual(root, DeepEqualOptions{}) {
+				t.Errorf("got %v", got)
+			}
+			if leaf := got.Children[0].Children[0].Children[0]; leaf.Name != "Leaf" || leaf.Children != nil {
+				t.Errorf("got leaf %s with children %v", leaf.Name, leaf.Children)
+			}
+		})
+	}
+}
@@ -1,8 +1,18 @@
 package lslib
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
 )
 
 type LSMetadata struct {
@@ -23,12 +33,274 @@ type format struct {
 type Resource struct {
 	Metadata LSMetadata `xml:"version"`
 	Regions  []*Node    `xml:"region"`
+
+	// checksum is the result of the last call to Checksum, it is nil until Checksum is called
+	// and after ResetChecksum
+	checksum *[sha256.Size]byte
 }
 
 func (r *Resource) Read(io.Reader) {
 
 }
 
+// UnmarshalXML reads the version element and the root node of each region element of an LSX save element
+func (r *Resource) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	r.checksum = nil
+	return lsxWalker{h: &lsxTreeBuilder{res: r, preserveExtraAttrs: true}}.save(d)
+}
+
+// Region returns the root node of the region named name
+func (r *Resource) Region(name string) (*Node, bool) {
+	for _, region := range r.Regions {
+		if region.RegionName == name {
+			return region, true
+		}
+	}
+	return nil, false
+}
+
+// AllNodes returns a channel yielding every node of every region of r depth first, it is closed after the last node.
+// The nodes are sent by a separate goroutine which only exits once every node has been received, use
+// AllNodesContext to stop early.
+func (r *Resource) AllNodes() <-chan *Node {
+	return r.AllNodesContext(context.Background())
+}
+
+// AllNodesContext is AllNodes, the channel is also closed when ctx is done
+func (r *Resource) AllNodesContext(ctx context.Context) <-chan *Node {
+	nodes := make(chan *Node)
+	go func() {
+		defer close(nodes)
+		for _, region := range r.Regions {
+			if !region.walk(func(n *Node) bool {
+				select {
+				case nodes <- n:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			}) {
+				return
+			}
+		}
+	}()
+	return nodes
+}
+
+// AllAttributes returns a channel yielding every attribute of every node of r, the nodes are visited depth first.
+// It is closed after the last attribute, use AllAttributesContext to stop early.
+func (r *Resource) AllAttributes() <-chan NodeAttribute {
+	return r.AllAttributesContext(context.Background())
+}
+
+// AllAttributesContext is AllAttributes, the channel is also closed when ctx is done
+func (r *Resource) AllAttributesContext(ctx context.Context) <-chan NodeAttribute {
+	attrs := make(chan NodeAttribute)
+	go func() {
+		defer close(attrs)
+		for _, region := range r.Regions {
+			if !region.walk(func(n *Node) bool {
+				for _, attr := range n.Attributes {
+					select {
+					case attrs <- attr:
+					case <-ctx.Done():
+						return false
+					}
+				}
+				return true
+			}) {
+				return
+			}
+		}
+	}()
+	return attrs
+}
+
+// ProcessRegions calls fn for each region of r using at most workers goroutines, or one per CPU if workers
+// is less than 1. The errors returned by fn are collected in the order of the regions.
+//
+// fn receives a read-only view of the region: it must not modify the region or any of its nodes, which may be
+// read by other calls at the same time, and must not keep the region after it returns.
+func ProcessRegions(r *Resource, workers int, fn func(*Node) error) []error {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var (
+		wg      sync.WaitGroup
+		regions = make(chan int)
+		results = make([]error, len(r.Regions))
+	)
+	for i := 0; i < workers && i < len(r.Regions); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range regions {
+				err := fn(r.Regions[i])
+				if err != nil {
+					results[i] = fmt.Errorf("region %s: %w", r.Regions[i].RegionName, err)
+				}
+			}
+		}()
+	}
+	for i := range r.Regions {
+		regions <- i
+	}
+	close(regions)
+	wg.Wait()
+
+	var errs []error
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Checksum returns a SHA-256 hash of the metadata, nodes and attributes of r, equal resources have equal
+// checksums. The hash is computed on the first call and kept in r, later calls return it without walking the
+// tree. Call ResetChecksum after modifying r or its nodes, otherwise Checksum and Equal use the old hash.
+func (r *Resource) Checksum() [sha256.Size]byte {
+	if r.checksum != nil {
+		return *r.checksum
+	}
+	var (
+		h    = sha256.New()
+		sum  [sha256.Size]byte
+		hash func(n *Node)
+	)
+	hash = func(n *Node) {
+		fmt.Fprintf(h, "node %q %q %d %d\n", n.Name, n.RegionName, len(n.Attributes), len(n.Children))
+		for _, attr := range n.Attributes {
+			fmt.Fprintf(h, "attribute %q %d %q\n", attr.Name, attr.Type, attr.String())
+		}
+		for _, child := range n.Children {
+			hash(child)
+		}
+	}
+	fmt.Fprintf(h, "%+v %d\n", r.Metadata, len(r.Regions))
+	for _, region := range r.Regions {
+		hash(region)
+	}
+	copy(sum[:], h.Sum(nil))
+	r.checksum = &sum
+	return sum
+}
+
+// ResetChecksum discards the hash kept by Checksum, it must be called after r or one of its nodes is modified
+func (r *Resource) ResetChecksum() {
+	r.checksum = nil
+}
+
+// Equal reports whether r and other hold the same metadata, nodes and attributes. If Checksum has been called on
+// both resources their checksums are compared first and resources with different checksums are rejected without
+// walking the trees, matching checksums are confirmed by comparing the trees. Equal never computes a checksum
+// itself: to find identical files among many resources, call Checksum once on each of them and then use Equal.
+func (r *Resource) Equal(other *Resource) bool {
+	if r.checksum != nil && other.checksum != nil && *r.checksum != *other.checksum {
+		return false
+	}
+	if r.Metadata != other.Metadata || len(r.Regions) != len(other.Regions) {
+		return false
+	}
+	for i := range r.Regions {
+		if !nodesEqual(r.Regions[i], other.Regions[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func nodesEqual(a, b *Node) bool {
+	if a.Name != b.Name || a.RegionName != b.RegionName || len(a.Attributes) != len(b.Attributes) || len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i, attr := range a.Attributes {
+		if !attr.Equal(b.Attributes[i]) {
+			return false
+		}
+	}
+	for i := range a.Children {
+		if !nodesEqual(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeepEqualOptions relaxes the comparison made by Node.DeepEqual
+type DeepEqualOptions struct {
+	// IgnoreOrder compares the attributes and the children of each node as sets instead of in order
+	IgnoreOrder bool
+	// FloatEpsilon is the largest difference allowed between float, vector and matrix components
+	FloatEpsilon float64
+	// IgnoreAttributeNames lists attributes that are skipped on both sides
+	IgnoreAttributeNames []string
+	// IgnoreNodeNames lists nodes that are skipped, with their children, on both sides
+	IgnoreNodeNames []string
+}
+
+// DeepEqual reports whether n and other have the same names, attributes and children as relaxed by opts
+func (n *Node) DeepEqual(other *Node, opts DeepEqualOptions) bool {
+	if n == nil || other == nil {
+		return n == other
+	}
+	if n.Name != other.Name || n.RegionName != other.RegionName {
+		return false
+	}
+
+	keepAttr := func(attr NodeAttribute) bool { return !contains(opts.IgnoreAttributeNames, attr.Name) }
+	keepNode := func(node *Node) bool { return !contains(opts.IgnoreNodeNames, node.Name) }
+	attrEqual := func(a, b NodeAttribute) bool { return a.equalWithin(b, opts.FloatEpsilon) }
+	nodeEqual := func(a, b *Node) bool { return a.DeepEqual(b, opts) }
+
+	return sliceEqual(keep(n.Attributes, keepAttr), keep(other.Attributes, keepAttr), attrEqual, opts.IgnoreOrder) &&
+		sliceEqual(keep(n.Children, keepNode), keep(other.Children, keepNode), nodeEqual, opts.IgnoreOrder)
+}
+
+// keep returns the elements of s for which include is true
+func keep[T any](s []T, include func(T) bool) []T {
+	var kept []T
+	for _, v := range s {
+		if include(v) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// sliceEqual reports whether a and b have equal elements in the same order, or in any order if ignoreOrder is set
+func sliceEqual[T any](a, b []T, equal func(a, b T) bool, ignoreOrder bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if !ignoreOrder {
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Each element of a is matched to the first unmatched equal element of b
+	matched := make([]bool, len(b))
+	for _, x := range a {
+		found := false
+		for j, y := range b {
+			if !matched[j] && equal(x, y) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // public Resource()
 // {
 //     Metadata.MajorVersion = 3;
@@ -43,43 +315,113 @@ type Node struct {
 	RegionName string `xml:"-"`
 }
 
-func (n Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
-	R := xml.Name{
-		Local: "region",
+// NewNode returns a node named name holding attributes
+func NewNode(name string, attributes ...NodeAttribute) *Node {
+	return &Node{
+		Name:       name,
+		Attributes: attributes,
 	}
-	N := xml.Name{
-		Local: "node",
+}
+
+// NewRegion returns a root node for the region name
+func NewRegion(name string) *Node {
+	return &Node{
+		Name:       name,
+		RegionName: name,
 	}
-	I := xml.Name{
-		Local: "id",
+}
+
+// Attribute returns the attribute of n named name
+func (n *Node) Attribute(name string) (*NodeAttribute, bool) {
+	for i := range n.Attributes {
+		if n.Attributes[i].Name == name {
+			return &n.Attributes[i], true
+		}
 	}
-	C := xml.Name{
-		Local: "children",
+	return nil, false
+}
+
+// GetOrCreateAttribute returns the attribute of n named name, if n has none an attribute holding the zero value of
+// dt is appended to n. dt is ignored if the attribute exists.
+func (n *Node) GetOrCreateAttribute(name string, dt DataType) (*NodeAttribute, error) {
+	if attr, ok := n.Attribute(name); ok {
+		return attr, nil
+	}
+	attr, err := NewNodeAttribute(name, dt)
+	if err != nil {
+		return nil, fmt.Errorf("node %s: %w", n.Name, err)
 	}
+	n.Attributes = append(n.Attributes, attr)
+	return &n.Attributes[len(n.Attributes)-1], nil
+}
+
+// MarshalXML writes n as a node element, nested in a region element if n is the root of a region
+func (n Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return n.MarshalXMLWithOpts(e, start, MarshalXMLOptions{})
+}
+
+// MarshalXMLWithOpts is MarshalXML, the attributes of n and its children are written using opts
+func (n Node) MarshalXMLWithOpts(e *xml.Encoder, start xml.StartElement, opts MarshalXMLOptions) error {
+	var (
+		R = xml.StartElement{
+			Name: xml.Name{Local: "region"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: n.RegionName}},
+		}
+		N = xml.StartElement{
+			Name: xml.Name{Local: "node"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: n.Name}},
+		}
+		C   = xml.StartElement{Name: xml.Name{Local: "children"}}
+		A   = xml.StartElement{Name: xml.Name{Local: "attribute"}}
+		err error
+	)
 	if n.RegionName != "" {
-		tmp := xml.StartElement{
-			Name: R,
-			Attr: []xml.Attr{{Name: I, Value: n.Name}},
+		err = e.EncodeToken(R)
+		if err != nil {
+			return err
+		}
+	}
+	err = e.EncodeToken(N)
+	if err != nil {
+		return err
+	}
+	for _, attr := range n.Attributes {
+		err = attr.MarshalXMLWithOpts(e, A, opts)
+		if err != nil {
+			return err
 		}
-		e.EncodeToken(tmp)
 	}
-	e.EncodeToken(xml.StartElement{
-		Name: N,
-		Attr: []xml.Attr{{Name: I, Value: n.Name}},
-	})
-	e.EncodeElement(n.Attributes, xml.StartElement{Name: xml.Name{Local: "attribute"}})
 	if len(n.Children) > 0 {
-		e.EncodeToken(xml.StartElement{Name: C})
-		e.Encode(n.Children)
-		e.EncodeToken(xml.EndElement{Name: C})
+		err = e.EncodeToken(C)
+		if err != nil {
+			return err
+		}
+		for _, child := range n.Children {
+			err = child.MarshalXMLWithOpts(e, N, opts)
+			if err != nil {
+				return err
+			}
+		}
+		err = e.EncodeToken(C.End())
+		if err != nil {
+			return err
+		}
+	}
+	err = e.EncodeToken(N.End())
+	if err != nil {
+		return err
 	}
-	e.EncodeToken(xml.EndElement{Name: N})
 	if n.RegionName != "" {
-		e.EncodeToken(xml.EndElement{Name: R})
+		return e.EncodeToken(R.End())
 	}
 	return nil
 }
 
+// UnmarshalXML reads a node element, its attribute elements and the node elements in its children element
+func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return lsxWalker{h: &lsxTreeBuilder{root: n, preserveExtraAttrs: true}}.node(d, start)
+}
+
 func (n Node) ChildCount() (sum int) {
 	// for _, v := range n.Children {
 	// 	sum += len(v)
@@ -87,10 +429,260 @@ func (n Node) ChildCount() (sum int) {
 	return len(n.Children)
 }
 
+// RecursiveNodeCount returns the number of nodes in the subtree rooted at n, including n
+func (n *Node) RecursiveNodeCount() int {
+	count := 0
+	n.walk(func(*Node) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// RecursiveAttributeCount returns the number of attributes in the subtree rooted at n, including those of n
+func (n *Node) RecursiveAttributeCount() int {
+	count := 0
+	n.walk(func(node *Node) bool {
+		count += len(node.Attributes)
+		return true
+	})
+	return count
+}
+
+// ChildAt returns the child of n at position index
+func (n *Node) ChildAt(index int) (*Node, bool) {
+	if index < 0 || index >= len(n.Children) {
+		return nil, false
+	}
+	return n.Children[index], true
+}
+
+// LastChild returns the last child of n
+func (n *Node) LastChild() (*Node, bool) {
+	return n.ChildAt(len(n.Children) - 1)
+}
+
+// FindPath returns the node reached by following the slash separated segments of path from n. A segment is the
+// name of a child, "." for the current node, ".." for its parent, or a name followed by an index in brackets such as
+// "Item[2]" for the third child with that name. The error wraps ErrNodeNotFound and names the segment that failed.
+func (n *Node) FindPath(path string) (*Node, error) {
+	node := n
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case ".":
+			continue
+		case "..":
+			if node.Parent == nil {
+				return nil, fmt.Errorf("path %q: segment %q: %s has no parent: %w", path, segment, node.Name, ErrNodeNotFound)
+			}
+			node = node.Parent
+			continue
+		}
+
+		name, index := segment, 0
+		if before, after, ok := strings.Cut(segment, "["); ok {
+			i, err := strconv.Atoi(strings.TrimSuffix(after, "]"))
+			if err != nil || !strings.HasSuffix(after, "]") || i < 0 {
+				return nil, fmt.Errorf("path %q: segment %q has an invalid index", path, segment)
+			}
+			name, index = before, i
+		}
+		var next *Node
+		for _, child := range node.Children {
+			if child.Name != name {
+				continue
+			}
+			if index == 0 {
+				next = child
+				break
+			}
+			index--
+		}
+		if next == nil {
+			return nil, fmt.Errorf("path %q: segment %q: %w", path, segment, ErrNodeNotFound)
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// AppendChild appends child to n and sets its parent to n
 func (n *Node) AppendChild(child *Node) {
+	child.Parent = n
 	n.Children = append(n.Children, child)
 }
 
+// AddChildren appends children to n and sets their parent to n.
+// No child is added if any of them is n or one of its ancestors.
+func (n *Node) AddChildren(children ...*Node) error {
+	for _, child := range children {
+		for p := n; p != nil; p = p.Parent {
+			if p == child {
+				return fmt.Errorf("adding %s to %s: %w", child.Name, n.Name, ErrNodeCycle)
+			}
+		}
+	}
+	for _, child := range children {
+		child.Parent = n
+	}
+	n.Children = append(n.Children, children...)
+	return nil
+}
+
+// ClearChildren removes all children of n and clears their parent
+func (n *Node) ClearChildren() {
+	for _, child := range n.Children {
+		child.Parent = nil
+	}
+	n.Children = nil
+}
+
+// SortChildrenBy sorts the children of n by the string returned by key, children with equal keys keep their order.
+// key is called once per child.
+func (n *Node) SortChildrenBy(key func(*Node) string) {
+	keys := make(map[*Node]string, len(n.Children))
+	for _, child := range n.Children {
+		keys[child] = key(child)
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return keys[n.Children[i]] < keys[n.Children[j]]
+	})
+}
+
+// ClearAttributes removes all attributes of n
+func (n *Node) ClearAttributes() {
+	n.Attributes = nil
+}
+
+// ApplyTemplate copies each attribute of template that n does not have to n, attributes n already has are
+// overrides and are kept. The children of template are not copied.
+func (n *Node) ApplyTemplate(template *Node) error {
+	if template == nil {
+		return fmt.Errorf("node %s: template is nil", n.Name)
+	}
+	names := n.AttributeNameSet()
+	for _, attr := range template.Attributes {
+		if _, ok := names[attr.Name]; ok {
+			continue
+		}
+		names[attr.Name] = struct{}{}
+		n.Attributes = append(n.Attributes, attr.Clone())
+	}
+	return nil
+}
+
+// AttributeNameSet returns the set of attribute names on n
+func (n *Node) AttributeNameSet() map[string]struct{} {
+	set := make(map[string]struct{}, len(n.Attributes))
+	for _, attr := range n.Attributes {
+		set[attr.Name] = struct{}{}
+	}
+	return set
+}
+
+// FindNodeByAttributeValue returns the first node in n and its descendants, depth first,
+// with an attribute named attrName whose value equals val
+func (n *Node) FindNodeByAttributeValue(attrName string, val interface{}) (*Node, bool) {
+	var found *Node
+	n.walk(func(node *Node) bool {
+		if node.hasAttributeValue(attrName, val) {
+			found = node
+			return false
+		}
+		return true
+	})
+	return found, found != nil
+}
+
+// FindAllNodesByAttributeValue returns every node in n and its descendants, depth first,
+// with an attribute named attrName whose value equals val
+func (n *Node) FindAllNodesByAttributeValue(attrName string, val interface{}) []*Node {
+	var found []*Node
+	n.walk(func(node *Node) bool {
+		if node.hasAttributeValue(attrName, val) {
+			found = append(found, node)
+		}
+		return true
+	})
+	return found
+}
+
+// walk calls fn for n and each of its descendants depth first until fn returns false
+func (n *Node) walk(fn func(*Node) bool) bool {
+	if !fn(n) {
+		return false
+	}
+	for _, child := range n.Children {
+		if !child.walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Node) hasAttributeValue(attrName string, val interface{}) bool {
+	for _, attr := range n.Attributes {
+		if attr.Name == attrName && attr.Equal(NodeAttribute{Name: attrName, Type: attr.Type, Value: val}) {
+			return true
+		}
+	}
+	return false
+}
+
+// AttributeValueIndex maps the values of one attribute to the nodes holding them
+type AttributeValueIndex struct {
+	Name  string
+	nodes map[string][]*Node
+}
+
+// BuildAttributeValueIndex indexes the values of the attribute attrName in n and its descendants,
+// use it instead of FindNodeByAttributeValue when looking up many values of the same attribute
+func (n *Node) BuildAttributeValueIndex(attrName string) *AttributeValueIndex {
+	idx := &AttributeValueIndex{
+		Name:  attrName,
+		nodes: make(map[string][]*Node),
+	}
+	n.walk(func(node *Node) bool {
+		for _, attr := range node.Attributes {
+			if attr.Name == attrName {
+				key := attributeValueKey(attr.Value)
+				if nodes := idx.nodes[key]; len(nodes) == 0 || nodes[len(nodes)-1] != node {
+					idx.nodes[key] = append(nodes, node)
+				}
+			}
+		}
+		return true
+	})
+	return idx
+}
+
+// attributeValueKey returns a string that is identical for equal values,
+// unequal values may share a key so matches must still be compared
+func attributeValueKey(v interface{}) string {
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+// Find returns the first indexed node, depth first, whose attribute equals val
+func (idx *AttributeValueIndex) Find(val interface{}) (*Node, bool) {
+	for _, node := range idx.nodes[attributeValueKey(val)] {
+		if node.hasAttributeValue(idx.Name, val) {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// FindAll returns every indexed node, depth first, whose attribute equals val
+func (idx *AttributeValueIndex) FindAll(val interface{}) []*Node {
+	var found []*Node
+	for _, node := range idx.nodes[attributeValueKey(val)] {
+		if node.hasAttributeValue(idx.Name, val) {
+			found = append(found, node)
+		}
+	}
+	return found
+}
+
 //      int TotalChildCount()
 // {
 //     int count = 0;
@@ -104,3 +696,125 @@ func (n *Node) AppendChild(child *Node) {
 
 //     return count;
 // }
+
+// UsedDataTypes returns the distinct types of all attributes in r in ascending order
+func (r *Resource) UsedDataTypes() []DataType {
+	var (
+		seen  = make(map[DataType]bool)
+		types []DataType
+	)
+	for _, region := range r.Regions {
+		region.walk(func(n *Node) bool {
+			for _, attr := range n.Attributes {
+				if !seen[attr.Type] {
+					seen[attr.Type] = true
+					types = append(types, attr.Type)
+				}
+			}
+			return true
+		})
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// AttributeRef locates an attribute in a Resource
+type AttributeRef struct {
+	Node *Node
+	// Path is the slash separated names of the region and nodes leading to Node
+	Path      string
+	Attribute string
+	// Offset is the byte offset of the finding in the string value
+	Offset int
+}
+
+// isInvisibleRune reports whether r is a byte order mark or zero-width character
+func isInvisibleRune(r rune) bool {
+	switch r {
+	case '\ufeff', '\u200b', '\u200c', '\u200d', '\u2060':
+		return true
+	}
+	return false
+}
+
+// FindEmbeddedBOMs returns every string attribute of r whose value contains a byte order mark or a zero-width
+// character, the value of translated strings is checked as well
+func (r *Resource) FindEmbeddedBOMs() []AttributeRef {
+	var (
+		refs  []AttributeRef
+		check func(n *Node, path string)
+	)
+	check = func(n *Node, path string) {
+		path += "/" + n.Name
+		for _, attr := range n.Attributes {
+			str, err := attr.GetString()
+			if err != nil {
+				continue
+			}
+			if i := strings.IndexFunc(str, isInvisibleRune); i >= 0 {
+				refs = append(refs, AttributeRef{Node: n, Path: path[1:], Attribute: attr.Name, Offset: i})
+			}
+		}
+		for _, child := range n.Children {
+			check(child, path)
+		}
+	}
+	for _, region := range r.Regions {
+		check(region, "")
+	}
+	return refs
+}
+
+// ReplaceAttributeValues calls replace for every attribute in r for which match is true and returns the number of
+// attributes replaced. replace is given a copy of the attribute which is stored back if replace returns nil, an
+// attribute for which replace returns an error is left unchanged and is not counted. The checksum of r is reset.
+func (r *Resource) ReplaceAttributeValues(match func(NodeAttribute) bool, replace func(*NodeAttribute) error) int {
+	r.checksum = nil
+	count := 0
+	for _, region := range r.Regions {
+		region.walk(func(n *Node) bool {
+			for i, attr := range n.Attributes {
+				if !match(attr) {
+					continue
+				}
+				replaced := attr.Clone()
+				if replace(&replaced) != nil {
+					continue
+				}
+				n.Attributes[i] = replaced
+				count++
+			}
+			return true
+		})
+	}
+	return count
+}
+
+// checkNilUUID returns ErrNilUUID with the path to the first DT_UUID attribute holding uuid.Nil
+// that is not named in intentional
+func checkNilUUID(r *Resource, intentional map[string]bool) error {
+	var check func(n *Node, path string) error
+	check = func(n *Node, path string) error {
+		path += "/" + n.Name
+		for _, attr := range n.Attributes {
+			if attr.Type != DT_UUID || intentional[attr.Name] {
+				continue
+			}
+			if id, ok := attr.Value.(uuid.UUID); ok && id == uuid.Nil {
+				return fmt.Errorf("%s: attribute %s: %w", path[1:], attr.Name, ErrNilUUID)
+			}
+		}
+		for _, child := range n.Children {
+			if err := check(child, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, region := range r.Regions {
+		if err := check(region, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
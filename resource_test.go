@@ -0,0 +1,302 @@
+package lslib
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAttributeNameSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []NodeAttribute
+		want  map[string]struct{}
+	}{
+		{"empty", nil, map[string]struct{}{}},
+		{"names", []NodeAttribute{{Name: "A"}, {Name: "B"}}, map[string]struct{}{"A": {}, "B": {}}},
+		{"duplicates", []NodeAttribute{{Name: "A"}, {Name: "A"}}, map[string]struct{}{"A": {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Node{Name: "Node", Attributes: tt.attrs}
+			if got := n.AttributeNameSet(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddChildren(t *testing.T) {
+	root := NewRegion("Region")
+	a, b := &Node{Name: "A"}, &Node{Name: "B"}
+	if err := root.AddChildren(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != 2 || a.Parent != root || b.Parent != root {
+		t.Fatalf("children were not added to the region")
+	}
+
+	tests := []struct {
+		name     string
+		parent   *Node
+		children []*Node
+	}{
+		{"itself", a, []*Node{a}},
+		{"parent", a, []*Node{{Name: "C"}, root}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(tt.parent.Children)
+			err := tt.parent.AddChildren(tt.children...)
+			if !errors.Is(err, ErrNodeCycle) {
+				t.Fatalf("got error %v, want ErrNodeCycle", err)
+			}
+			if len(tt.parent.Children) != before || tt.children[0].Parent == tt.parent {
+				t.Error("children were added although the addition was rejected")
+			}
+		})
+	}
+}
+
+func TestResourceEqualNearMiss(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(res *Resource)
+		equal  bool
+		// sameChecksum is whether the checksums match, values that format alike have the same checksum
+		sameChecksum bool
+	}{
+		{"unchanged", func(*Resource) {}, true, true},
+		{"last value", func(res *Resource) {
+			root := res.Regions[0]
+			root.Children[len(root.Children)-1].Attributes[0].Value = int32(-1)
+		}, false, false},
+		{"attribute name", func(res *Resource) { res.Regions[0].Children[10].Attributes[0].Name = "Attribute1O" }, false, false},
+		{"value type", func(res *Resource) { res.Regions[0].Children[10].Attributes[0].Value = int64(10) }, false, true},
+		{"metadata", func(res *Resource) { res.Metadata.BuildNumber++ }, false, false},
+		{"float holding an int", func(res *Resource) {
+			res.Regions[0].Children[0].Attributes[0] = NodeAttribute{Name: "Attribute0", Type: DT_Float, Value: 0}
+		}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := namedResource(100), namedResource(100)
+			tt.change(b)
+			if got := a.Equal(b); got != tt.equal {
+				t.Errorf("Equal = %v, want %v", got, tt.equal)
+			}
+			if got := a.Checksum() == b.Checksum(); got != tt.sameChecksum {
+				t.Errorf("checksums equal = %v, want %v", got, tt.sameChecksum)
+			}
+		})
+	}
+}
+
+func BenchmarkResourceEqual(b *testing.B) {
+	x, y := namedResource(10000), namedResource(10000)
+	b.Run("Equal", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x.Equal(y)
+		}
+	})
+	b.Run("Checksum", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x.ResetChecksum()
+			y.ResetChecksum()
+			_ = x.Checksum() == y.Checksum()
+		}
+	})
+	// The last attribute differs, so the tree walk has to reach the end of the tree to reject z
+	z := namedResource(10000)
+	z.Regions[0].Children[9999].Attributes[0].Value = int32(-1)
+	b.Run("EqualLastDiffers", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x.Equal(z)
+		}
+	})
+	x.Checksum()
+	z.Checksum()
+	b.Run("EqualCachedChecksums", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x.Equal(z)
+		}
+	})
+}
+
+func TestResourceChecksumCache(t *testing.T) {
+	tests := []struct {
+		name string
+		// hashA and hashB are whether the checksums of a and b are computed before the change to b is undone
+		hashA, hashB bool
+		reset        bool
+		want         bool
+	}{
+		{"no checksums", false, false, false, true},
+		{"only a hashed", true, false, false, true},
+		{"only b hashed", false, true, false, true},
+		{"both hashed", true, true, false, false},
+		{"both hashed then reset", true, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := namedResource(10), namedResource(10)
+			attr := &b.Regions[0].Children[5].Attributes[0]
+			value := attr.Value
+			attr.Value = int32(-1)
+			if tt.hashA {
+				a.Checksum()
+			}
+			if tt.hashB {
+				b.Checksum()
+			}
+			// a and b are identical again, only a stale checksum can tell them apart
+			attr.Value = value
+			if tt.reset {
+				b.ResetChecksum()
+			}
+			if got := a.Equal(b); got != tt.want {
+				t.Errorf("Equal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceNavigation(t *testing.T) {
+	config := NewRegion("Config")
+	templates := NewRegion("Templates")
+	for _, name := range []string{"A", "B"} {
+		n := &Node{Name: name, Attributes: []NodeAttribute{{Name: "Name", Type: DT_FixedString, Value: "item " + name}}}
+		if err := templates.AddChildren(n); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.AddChildren(&Node{Name: name + "1"}, &Node{Name: name + "2"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := &Resource{Regions: []*Node{config, templates}}
+
+	region, ok := res.Region("Templates")
+	if !ok || region != templates {
+		t.Fatalf("Region(Templates) = %v, %v", region, ok)
+	}
+	if _, ok := res.Region("Missing"); ok {
+		t.Error("Region(Missing) was found")
+	}
+	attr, ok := region.Children[1].Attribute("Name")
+	if !ok || attr.Value != "item B" {
+		t.Errorf("Attribute(Name) = %v, %v, want item B", attr, ok)
+	}
+	if _, ok := region.Children[1].Attribute("Missing"); ok {
+		t.Error("Attribute(Missing) was found")
+	}
+
+	var order []string
+	for n := range res.AllNodes() {
+		order = append(order, n.Name)
+	}
+	want := []string{"Config", "Templates", "A", "A1", "A2", "B", "B1", "B2"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("got nodes %v, want %v", order, want)
+	}
+}
+
+func TestFindEmbeddedBOMs(t *testing.T) {
+	root := NewRegion("Region")
+	child := &Node{Name: "Line", Parent: root, Attributes: []NodeAttribute{
+		{Name: "Clean", Type: DT_LSString, Value: "Hello"},
+		{Name: "BOM", Type: DT_LSString, Value: "Hel\ufefflo"},
+		{Name: "Text", Type: DT_TranslatedString, Value: TranslatedString{Handle: "h", Value: "\u200bHi"}},
+		{Name: "Number", Type: DT_Int, Value: int32(1)},
+	}}
+	root.Children = []*Node{child}
+	root.Attributes = []NodeAttribute{{Name: "Title", Type: DT_FixedString, Value: "ok"}}
+
+	got := (&Resource{Regions: []*Node{root}}).FindEmbeddedBOMs()
+	want := []AttributeRef{
+		{Node: child, Path: "Region/Line", Attribute: "BOM", Offset: 3},
+		{Node: child, Path: "Region/Line", Attribute: "Text", Offset: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUsedDataTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *Resource
+		want []DataType
+	}{
+		{"empty", &Resource{}, nil},
+		{"known tree", func() *Resource {
+			res := namedResource(3)
+			res.Regions[0].Attributes = []NodeAttribute{
+				{Name: "Name", Type: DT_LSString, Value: "x"},
+				{Name: "UUID", Type: DT_UUID, Value: uuid.New()},
+			}
+			res.Regions[0].Children[1].Attributes = append(res.Regions[0].Children[1].Attributes,
+				NodeAttribute{Name: "Flag", Type: DT_Bool, Value: true})
+			return res
+		}(), []DataType{DT_Int, DT_Bool, DT_LSString, DT_UUID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.res.UsedDataTypes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceAttributeValues(t *testing.T) {
+	var (
+		oldID = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+		newID = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+		other = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	)
+	res := namedResource(4)
+	root := res.Regions[0]
+	root.Attributes = []NodeAttribute{{Name: "ModUUID", Type: DT_UUID, Value: oldID}}
+	root.Children[0].Attributes = append(root.Children[0].Attributes, NodeAttribute{Name: "Owner", Type: DT_UUID, Value: oldID})
+	root.Children[1].Attributes = append(root.Children[1].Attributes, NodeAttribute{Name: "Owner", Type: DT_UUID, Value: other})
+	root.Children[2].Children = []*Node{{Name: "Ref", Parent: root.Children[2], Attributes: []NodeAttribute{{Name: "Target", Type: DT_UUID, Value: oldID}}}}
+	root.Children[3].Attributes = append(root.Children[3].Attributes, NodeAttribute{Name: "Text", Type: DT_LSString, Value: oldID.String()})
+
+	count := res.ReplaceAttributeValues(func(na NodeAttribute) bool {
+		return na.Type == DT_UUID && na.Value == oldID
+	}, func(na *NodeAttribute) error {
+		na.Value = newID
+		return nil
+	})
+	if count != 3 {
+		t.Errorf("replaced %d values, want 3", count)
+	}
+
+	tests := []struct {
+		attr NodeAttribute
+		want interface{}
+	}{
+		{root.Attributes[0], newID},
+		{root.Children[0].Attributes[1], newID},
+		{root.Children[1].Attributes[1], other},
+		{root.Children[2].Children[0].Attributes[0], newID},
+		{root.Children[3].Attributes[1], oldID.String()},
+	}
+	for _, tt := range tests {
+		if tt.attr.Value != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.attr.Name, tt.attr.Value, tt.want)
+		}
+	}
+
+	failed := res.ReplaceAttributeValues(func(na NodeAttribute) bool {
+		return na.Type == DT_UUID
+	}, func(na *NodeAttribute) error {
+		na.Value = uuid.Nil
+		return errors.New("rejected")
+	})
+	if failed != 0 || root.Attributes[0].Value != newID {
+		t.Errorf("a failed replacement changed %d values", failed)
+	}
+}
@@ -0,0 +1,103 @@
+package lslib
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Schema describes the attributes of a resource without their values. It maps the slash separated names of the
+// region and nodes leading to a node to the types of the attributes found on nodes at that path.
+type Schema map[string]map[string]DataType
+
+// ExtractSchema returns the schema of r. Nodes sharing a path contribute all of their attributes to it, if an
+// attribute has different types at the same path the first one is kept.
+func (r *Resource) ExtractSchema() Schema {
+	var (
+		schema  = Schema{}
+		extract func(n *Node, path string)
+	)
+	extract = func(n *Node, path string) {
+		path += "/" + n.Name
+		attrs, ok := schema[path[1:]]
+		if !ok {
+			attrs = map[string]DataType{}
+			schema[path[1:]] = attrs
+		}
+		for _, attr := range n.Attributes {
+			if _, ok := attrs[attr.Name]; !ok {
+				attrs[attr.Name] = attr.Type
+			}
+		}
+		for _, child := range n.Children {
+			extract(child, path)
+		}
+	}
+	for _, region := range r.Regions {
+		extract(region, "")
+	}
+	return schema
+}
+
+// Validate checks that other conforms to s: every node path of other is in s, each attribute has the type given
+// by s, and the nodes at each path together have every attribute of s. The returned errors wrap ErrSchemaMismatch
+// and are ordered by the position of the nodes in other, followed by the paths missing from other.
+func (s Schema) Validate(other *Resource) []error {
+	var (
+		errs     []error
+		seen     = Schema{}
+		validate func(n *Node, path string)
+	)
+	validate = func(n *Node, path string) {
+		path += "/" + n.Name
+		attrs, ok := s[path[1:]]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: node is not in the schema: %w", path[1:], ErrSchemaMismatch))
+			return
+		}
+		found, ok := seen[path[1:]]
+		if !ok {
+			found = map[string]DataType{}
+			seen[path[1:]] = found
+		}
+		for _, attr := range n.Attributes {
+			dt, ok := attrs[attr.Name]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("%s: attribute %s is not in the schema: %w", path[1:], attr.Name, ErrSchemaMismatch))
+			case dt != attr.Type:
+				errs = append(errs, fmt.Errorf("%s: attribute %s is %v, expected %v: %w", path[1:], attr.Name, attr.Type, dt, ErrSchemaMismatch))
+			}
+			found[attr.Name] = attr.Type
+		}
+		for _, child := range n.Children {
+			validate(child, path)
+		}
+	}
+	for _, region := range other.Regions {
+		validate(region, "")
+	}
+
+	for _, path := range sortedKeys(s) {
+		found, ok := seen[path]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: node is missing: %w", path, ErrSchemaMismatch))
+			continue
+		}
+		for _, name := range sortedKeys(s[path]) {
+			if _, ok := found[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s: attribute %s is missing: %w", path, name, ErrSchemaMismatch))
+			}
+		}
+	}
+	return errs
+}
+
+// sortedKeys returns the keys of m in increasing order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
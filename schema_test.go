@@ -0,0 +1,61 @@
+package lslib
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema := namedResource(2).ExtractSchema()
+	want := Schema{
+		"Region":       {},
+		"Region/Node0": {"Attribute0": DT_Int},
+		"Region/Node1": {"Attribute1": DT_Int},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Fatalf("got schema %v, want %v", schema, want)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(res *Resource)
+		want   []string
+	}{
+		{"conforming", func(res *Resource) {}, nil},
+		{"conforming with other values", func(res *Resource) {
+			res.Regions[0].Children[0].Attributes[0].Value = int32(99)
+		}, nil},
+		{"changed type", func(res *Resource) {
+			res.Regions[0].Children[0].Attributes[0] = NodeAttribute{Name: "Attribute0", Type: DT_UInt, Value: uint32(0)}
+		}, []string{"Region/Node0: attribute Attribute0 is uint32, expected int32: resource does not match the schema"}},
+		{"extra attribute", func(res *Resource) {
+			res.Regions[0].Attributes = []NodeAttribute{{Name: "Extra", Type: DT_Bool, Value: true}}
+		}, []string{"Region: attribute Extra is not in the schema: resource does not match the schema"}},
+		{"missing attribute", func(res *Resource) {
+			res.Regions[0].Children[1].Attributes = nil
+		}, []string{"Region/Node1: attribute Attribute1 is missing: resource does not match the schema"}},
+		{"extra and missing node", func(res *Resource) {
+			res.Regions[0].Children[1].Name = "Other"
+		}, []string{
+			"Region/Other: node is not in the schema: resource does not match the schema",
+			"Region/Node1: node is missing: resource does not match the schema",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := namedResource(2)
+			tt.modify(res)
+			var got []string
+			for _, err := range schema.Validate(res) {
+				if !errors.Is(err, ErrSchemaMismatch) {
+					t.Errorf("%v does not wrap ErrSchemaMismatch", err)
+				}
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
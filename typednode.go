@@ -0,0 +1,226 @@
+package lslib
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// TypedNode maps the attributes of a node onto the fields of the struct T.
+//
+// Each exported field of T is read from and written to the attribute named by its lsx tag, or by the field
+// name if it has no tag. A field tagged "-" is ignored. The tag may name the DataType used when Store adds
+// the attribute, e.g. `lsx:"MapKey,FixedString"`, otherwise it is derived from the type of the field.
+//
+//	type Item struct {
+//		MapKey uuid.UUID
+//		Name   string `lsx:"Name,FixedString"`
+//		Amount int32  `lsx:"Amount"`
+//	}
+type TypedNode[T any] struct {
+	*Node
+	schema T
+}
+
+// NewTypedNode returns n mapped onto T
+func NewTypedNode[T any](n *Node) TypedNode[T] {
+	return TypedNode[T]{Node: n}
+}
+
+type typedField struct {
+	index int
+	name  string
+	dt    DataType
+}
+
+func (tn TypedNode[T]) fields() ([]typedField, error) {
+	t := reflect.TypeOf(tn.schema)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("TypedNode schema %v is not a struct", t)
+	}
+	var fields []typedField
+	for i := 0; i < t.NumField(); i++ {
+		var (
+			sf    = t.Field(i)
+			field = typedField{index: i, name: sf.Name, dt: DT_None}
+			err   error
+		)
+		if sf.PkgPath != "" {
+			continue
+		}
+		if tag, ok := sf.Tag.Lookup("lsx"); ok {
+			if tag == "-" {
+				continue
+			}
+			name, dt, hasType := strings.Cut(tag, ",")
+			if name != "" {
+				field.name = name
+			}
+			if hasType {
+				field.dt, err = ParseDataType(dt)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Load returns the attributes of the node as a T, fields whose attribute is missing keep their zero value.
+// Vectors, matrices and buffers are copied, modifying the result does not modify the node.
+func (tn TypedNode[T]) Load() (T, error) {
+	var v T
+	fields, err := tn.fields()
+	if err != nil {
+		return v, err
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	for _, field := range fields {
+		attr, ok := tn.Attribute(field.name)
+		if !ok || attr.Value == nil {
+			continue
+		}
+		fv := rv.Field(field.index)
+		// The value is cloned so that v does not share the vectors, matrices and buffers of the node
+		value, err := convertValue(reflect.ValueOf(attr.Clone().Value), fv.Type())
+		if err != nil {
+			return v, fmt.Errorf("attribute %s of type %v: %w", attr.Name, attr.Type, err)
+		}
+		fv.Set(value)
+	}
+	return v, nil
+}
+
+// Store sets the attributes of the node from v. Existing attributes keep their DataType,
+// missing attributes are appended using the type from the field tag or the type of the field.
+// Vectors, matrices and buffers are copied, modifying v afterwards does not modify the node.
+func (tn TypedNode[T]) Store(v T) error {
+	fields, err := tn.fields()
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	for _, field := range fields {
+		var (
+			fv      = rv.Field(field.index)
+			attr, _ = tn.Attribute(field.name)
+			dt      = field.dt
+		)
+		if attr != nil {
+			dt = attr.Type
+		}
+		if dt == DT_None {
+			dt = dataTypeOf(fv.Type())
+		}
+		goType := dt.goType()
+		if goType == nil {
+			return fmt.Errorf("field %s: no DataType for %v", field.name, fv.Type())
+		}
+		value, err := convertValue(fv, goType)
+		if err != nil {
+			return fmt.Errorf("field %s can not be stored as %v: %w", field.name, dt, err)
+		}
+
+		if attr == nil {
+			tn.Attributes = append(tn.Attributes, NodeAttribute{Name: field.name, Type: dt})
+			attr = &tn.Attributes[len(tn.Attributes)-1]
+		}
+		attr.Type = dt
+		attr.Value = value.Interface()
+		attr.Value = attr.Clone().Value
+	}
+	return nil
+}
+
+// convertValue converts v to t if it is assignable, or if both are numbers or share the same kind.
+// Numbers converted to an integer type must keep their value, otherwise the error wraps strconv.ErrRange.
+func convertValue(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if !v.Type().ConvertibleTo(t) || !(isNumber(v.Kind()) && isNumber(t.Kind()) || v.Kind() == t.Kind()) {
+		return v, fmt.Errorf("%v can not be converted to %v", v.Type(), t)
+	}
+	if isNumber(v.Kind()) && !fitsInteger(v, t) {
+		return v, fmt.Errorf("%v does not fit in %v: %w", v, t, strconv.ErrRange)
+	}
+	return v.Convert(t), nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64
+}
+
+// dataTypeOf returns the DataType whose Go type is t, types shared by several DataTypes
+// map to the most common one and vectors and matrices must be given a DataType explicitly.
+// int and uint map to the 32-bit DT_Int and DT_UInt, Store rejects values outside their range.
+func dataTypeOf(t reflect.Type) DataType {
+	switch t {
+	case reflect.TypeOf(""):
+		return DT_LSString
+	case reflect.TypeOf(int64(0)):
+		return DT_Int64
+	case reflect.TypeOf(0):
+		return DT_Int
+	case reflect.TypeOf(uint(0)):
+		return DT_UInt
+	case reflect.TypeOf(Ivec(nil)), reflect.TypeOf(Vec(nil)), reflect.TypeOf(&Mat{}):
+		return DT_None
+	}
+	for dt := DT_None; dt <= DT_Max; dt++ {
+		if goType := dt.goType(); goType != nil && goType == t {
+			return dt
+		}
+	}
+	return DT_None
+}
+
+// ReadMapKey returns the MapKey attribute of n, the key of the entries of BG3 maps. It may be a DT_UUID or a string
+// holding a UUID.
+func ReadMapKey(n *Node) (uuid.UUID, error) {
+	return attributeUUID(n, "MapKey")
+}
+
+// ReadNameAndUUID returns the Name and UUID attributes of n, which identify most BG3 game objects.
+// The UUID attribute may be a DT_UUID or a string holding a UUID.
+func ReadNameAndUUID(n *Node) (name string, id uuid.UUID, err error) {
+	attr, ok := n.Attribute("Name")
+	if !ok {
+		return "", uuid.Nil, fmt.Errorf("node %s has no Name attribute", n.Name)
+	}
+	name, err = attr.GetString()
+	if err != nil {
+		return "", uuid.Nil, fmt.Errorf("node %s: %w", n.Name, err)
+	}
+	id, err = attributeUUID(n, "UUID")
+	return name, id, err
+}
+
+// attributeUUID returns the attribute of n named name as a UUID, string attributes are parsed
+func attributeUUID(n *Node, name string) (uuid.UUID, error) {
+	attr, ok := n.Attribute(name)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("node %s has no %s attribute", n.Name, name)
+	}
+	if attr.Type.IsString() {
+		str, err := attr.GetString()
+		if err == nil {
+			var id uuid.UUID
+			id, err = uuid.Parse(str)
+			if err == nil {
+				return id, nil
+			}
+		}
+		return uuid.Nil, fmt.Errorf("node %s: attribute %s: %w", n.Name, name, err)
+	}
+	id, err := attr.GetUUID()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("node %s: %w", n.Name, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,143 @@
+package lslib
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"gonum.org/v1/gonum/mat"
+)
+
+type typedItem struct {
+	MapKey  uuid.UUID
+	Name    string `lsx:"Name,FixedString"`
+	Amount  int
+	Level   uint8 `lsx:"Lvl"`
+	Weight  float32
+	Ignored string `lsx:"-"`
+	hidden  int
+}
+
+func TestTypedNodeRoundTrip(t *testing.T) {
+	want := typedItem{MapKey: uuid.MustParse("f5a0bc1b-7b9a-4a0c-8a0e-6d6f1f0b5f3a"), Name: "Sword", Amount: -3, Level: 7, Weight: 1.5}
+	n := &Node{Name: "Item"}
+	tn := NewTypedNode[typedItem](n)
+	if err := tn.Store(want); err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[string]DataType{"MapKey": DT_UUID, "Name": DT_FixedString, "Amount": DT_Int, "Lvl": DT_Byte, "Weight": DT_Float}
+	if len(n.Attributes) != len(types) {
+		t.Errorf("got %d attributes, want %d", len(n.Attributes), len(types))
+	}
+	for name, dt := range types {
+		if attr, ok := n.Attribute(name); !ok || attr.Type != dt {
+			t.Errorf("attribute %s: got %v, want %v", name, attr, dt)
+		}
+	}
+
+	got, err := tn.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestTypedNodeCopiesValues(t *testing.T) {
+	type shaped struct {
+		Position Vec    `lsx:"Position,fvec3"`
+		Cell     Ivec   `lsx:"Cell,ivec3"`
+		Buffer   []byte `lsx:"Buffer,ScratchBuffer"`
+		Rotation *Mat   `lsx:"Rotation,mat2x2"`
+	}
+	newValue := func() shaped {
+		m := Mat(*mat.NewDense(2, 2, []float64{1, 2, 3, 4}))
+		return shaped{Position: Vec{1, 2, 3}, Cell: Ivec{4, 5, 6}, Buffer: []byte{7, 8}, Rotation: &m}
+	}
+	modify := func(v shaped) {
+		v.Position[0] = -1
+		v.Cell[0] = -1
+		v.Buffer[0] = 0
+		(*mat.Dense)(v.Rotation).Set(0, 0, -1)
+	}
+	tests := []struct {
+		name string
+		// modified returns a value that would share its slices and matrix with the node if they were not copied
+		modified func(t *testing.T, tn TypedNode[shaped]) shaped
+	}{
+		{"Load", func(t *testing.T, tn TypedNode[shaped]) shaped {
+			v, err := tn.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		}},
+		{"Store", func(t *testing.T, tn TypedNode[shaped]) shaped {
+			v := newValue()
+			if err := tn.Store(v); err != nil {
+				t.Fatal(err)
+			}
+			return v
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tn := NewTypedNode[shaped](&Node{Name: "Item"})
+			if err := tn.Store(newValue()); err != nil {
+				t.Fatal(err)
+			}
+			modify(tt.modified(t, tn))
+			got, err := tn.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := newValue()
+			if !reflect.DeepEqual(got.Position, want.Position) || !reflect.DeepEqual(got.Cell, want.Cell) ||
+				!reflect.DeepEqual(got.Buffer, want.Buffer) || !mat.Equal((*mat.Dense)(got.Rotation), (*mat.Dense)(want.Rotation)) {
+				t.Errorf("modifying the %s value changed the node: got %+v", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestTypedNodeRange(t *testing.T) {
+	type small struct {
+		Byte uint8
+		Int  int
+	}
+	tests := []struct {
+		name    string
+		attrs   []NodeAttribute
+		wantErr bool
+		// outOfRange is whether the error wraps strconv.ErrRange
+		outOfRange bool
+	}{
+		{"fits", []NodeAttribute{{Name: "Byte", Type: DT_Int64, Value: int64(255)}, {Name: "Int", Type: DT_Long, Value: int64(-5)}}, false, false},
+		{"int64 300 in uint8", []NodeAttribute{{Name: "Byte", Type: DT_Int64, Value: int64(300)}}, true, true},
+		{"negative in uint8", []NodeAttribute{{Name: "Byte", Type: DT_Int, Value: int32(-1)}}, true, true},
+		{"fraction in int", []NodeAttribute{{Name: "Int", Type: DT_Double, Value: 1.5}}, true, true},
+		{"string in int", []NodeAttribute{{Name: "Int", Type: DT_LSString, Value: "1"}}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTypedNode[small](&Node{Name: "N", Attributes: tt.attrs}).Load()
+			if (err != nil) != tt.wantErr || errors.Is(err, strconv.ErrRange) != tt.outOfRange {
+				t.Errorf("got error %v, want error: %v, out of range: %v", err, tt.wantErr, tt.outOfRange)
+			}
+		})
+	}
+
+	// int fields are stored as DT_Int, which holds 32 bits
+	tn := NewTypedNode[small](&Node{Name: "N"})
+	if err := tn.Store(small{Int: math.MaxInt32 + 1}); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("storing MaxInt32+1: got error %v, want strconv.ErrRange", err)
+	}
+	if err := tn.Store(small{Int: math.MaxInt32}); err != nil {
+		t.Errorf("storing MaxInt32: %v", err)
+	}
+}